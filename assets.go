@@ -0,0 +1,70 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ---- The game's files ----
+//
+// The graphics, the web page and the brick layouts in the assets folder are
+// built into the game when it is compiled, so the game works wherever it is
+// run from. The go:embed line below tells the Go compiler to do this.
+//
+// The -assets setting names a folder of files that replace the ones built
+// into the game. Only the files in the folder are replaced, so a folder with
+// just a ball.png in it changes the ball and nothing else. The files in the
+// folder must be named and arranged the same way as the assets folder, for
+// example mytheme/graphics/ball.png.
+
+// The files built into the game.
+//
+//go:embed assets
+var builtInAssets embed.FS
+
+// The folder of files that replace the built in ones. If it is empty only
+// the built in files are used.
+var assetsDirectory string
+
+// AddAssetFlags adds the command line flag for the assets folder.
+func addAssetFlags() {
+	flag.StringVar(&assetsDirectory, "assets", "", "a folder of graphics that replace the ones built into the game, for example mytheme/graphics/ball.png")
+}
+
+// CheckAssetFlags makes sure the assets folder is there.
+func checkAssetFlags() error {
+	if assetsDirectory == "" {
+		return nil
+	}
+	var info os.FileInfo
+	var err error
+	info, err = os.Stat(assetsDirectory)
+	if err != nil || info.IsDir() == false {
+		return badSetting("assets", "there is no folder called "+assetsDirectory)
+	}
+	return nil
+}
+
+// ReadAsset reads one of the game's files, for example "graphics/ball.png".
+// The name always uses / between folders, even on Windows. A file in the
+// assets folder is used if there is one, otherwise the built in file is.
+func readAsset(name string) ([]byte, error) {
+	if assetsDirectory != "" {
+		var data []byte
+		var err error
+		data, err = os.ReadFile(filepath.Join(assetsDirectory, filepath.FromSlash(name)))
+		if err == nil {
+			return data, nil
+		}
+		// a file that is there but can't be read is a mistake somebody
+		// needs to know about
+		if errors.Is(err, os.ErrNotExist) == false {
+			return nil, err
+		}
+	}
+	return builtInAssets.ReadFile(path.Join("assets", name))
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Breakout mode ----
+//
+// In breakout mode a wall of bricks sits in the middle of the playing field
+// between the two bats. When the ball hits a brick the brick breaks and the
+// player who last hit the ball with their bat scores a point. The game is over
+// when a player reaches their target score, or when there are no bricks left.
+
+// A brick is a single block in the wall. We need to know where it is, how big
+// it is and whether it has already been broken.
+// A struct lets us keep all of these things about one brick together.
+type brick struct {
+	x      int
+	y      int
+	w      int
+	h      int
+	broken bool
+}
+
+// The size of each brick in pixels
+const BrickWidth = 24
+const BrickHeight = 40
+
+// The gap between the bricks in pixels
+const BrickGap = 4
+
+// All of the bricks in the wall. We don't know how many bricks there will be
+// until we have read the layout, so we use a slice rather than an array.
+// A slice is like an array that can grow.
+var bricks []brick
+
+// The number of bricks that have not been broken yet. When this reaches zero
+// the game is over.
+var bricksLeft int
+
+// The name of the file that describes the layout of the bricks. If this is
+// empty we use the defaultBrickLayout instead.
+var brickLayoutFilename string
+
+// The rows of the brick layout for this match. We keep the layout so we can
+// send it to a player who joins over the network.
+var brickLayout []string
+
+// The brick layout used when there is no layout file.
+// Each line is one row of bricks. A # is a brick and a . is a gap.
+const defaultBrickLayout = `
+####
+####
+#..#
+#..#
+####
+####
+####
+####
+#..#
+#..#
+####
+####
+`
+
+// InitialiseBricks reads the brick layout and builds the wall of bricks in
+// the middle of the playing field.
+func initialiseBricks() {
+	if brickLayoutFilename == "" {
+		brickLayout = parseBrickLayout(strings.NewReader(defaultBrickLayout))
+	} else {
+		brickLayout = loadBrickLayout(brickLayoutFilename)
+	}
+	buildBricks(brickLayout)
+}
+
+// LoadBrickLayout reads a brick layout from a file or crashes trying. If there
+// is no such file, the layouts built into the game are tried, so
+// -bricks diamond.txt works wherever the game is run from.
+func loadBrickLayout(filename string) []string {
+	var file *os.File
+	var err error
+	file, err = os.Open(filename)
+	if os.IsNotExist(err) == true {
+		var data []byte
+		var assetErr error
+		data, assetErr = readAsset("bricks/" + filepath.Base(filename))
+		if assetErr == nil {
+			return parseBrickLayout(bytes.NewReader(data))
+		}
+	}
+	if err != nil {
+		fmt.Print("Failed to load brick layout: ")
+		fmt.Println(err)
+		panic(err)
+	}
+	defer file.Close()
+	return parseBrickLayout(file)
+}
+
+// ParseBrickLayout reads the rows of a brick layout.
+// Blank lines and lines that start with // are ignored, so a layout file can
+// have comments in it.
+func parseBrickLayout(file io.Reader) []string {
+	var rows []string
+	var scanner *bufio.Scanner
+	scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line string
+		line = strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		rows = append(rows, line)
+	}
+	if scanner.Err() != nil {
+		fmt.Print("Failed to read brick layout: ")
+		fmt.Println(scanner.Err())
+		panic(scanner.Err())
+	}
+	return rows
+}
+
+// BuildBricks turns the rows of a brick layout into bricks. The wall is
+// placed in the centre of the screen.
+func buildBricks(layout []string) {
+	// find the widest row so we can centre the wall
+	var columns int
+	var row string
+	for _, row = range layout {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	var wallW int
+	wallW = columns*(BrickWidth+BrickGap) - BrickGap
+	var wallH int
+	wallH = len(layout)*(BrickHeight+BrickGap) - BrickGap
+	var wallX int
+	wallX = windowWidth/2 - wallW/2
+	var wallY int
+	wallY = windowHeight/2 - wallH/2
+
+	bricks = nil
+	bricksLeft = 0
+	var r, c int
+	for r = 0; r < len(layout); r++ {
+		for c = 0; c < len(layout[r]); c++ {
+			// anything that is not a # is a gap in the wall
+			if layout[r][c] != '#' {
+				continue
+			}
+			var b brick
+			b.x = wallX + c*(BrickWidth+BrickGap)
+			b.y = wallY + r*(BrickHeight+BrickGap)
+			b.w = BrickWidth
+			b.h = BrickHeight
+			b.broken = false
+			bricks = append(bricks, b)
+			bricksLeft = bricksLeft + 1
+		}
+	}
+}
+
+// CheckForBallBrickCollisions breaks the first brick the ball has hit,
+// bounces the ball off it and gives a point to the player who last hit the
+// ball.
+func checkForBallBrickCollisions() {
+	var i int
+	for i = 0; i < len(bricks); i++ {
+		// we have to use a pointer here. If we did not the brick would be a
+		// copy, and breaking the copy would not break the brick in the wall.
+		var b *brick
+		b = &bricks[i]
+		if b.broken == true {
+			continue
+		}
+		if checkForBallBrickCollision(b) == false {
+			continue
+		}
+		reflectBallFromBrick(b)
+		b.broken = true
+		bricksLeft = bricksLeft - 1
+		// the point goes to whoever hit the ball last. If nobody has hit the
+		// ball since it was served nobody gets the point.
+		if lastHitBy == Player {
+			myScore = myScore + 1
+			notePoint(Player)
+			if myScore >= myTargetScore {
+				gameOver = true
+			}
+		} else if lastHitBy == Computer {
+			computersScore = computersScore + 1
+			notePoint(Computer)
+			if computersScore >= computersTargetScore {
+				gameOver = true
+			}
+		}
+		// when all the bricks are broken the game is over
+		if bricksLeft == 0 {
+			gameOver = true
+		}
+		// only break one brick each frame
+		return
+	}
+}
+
+// CheckForBallBrickCollision works just like the bat collision checks. We
+// look for an overlap between the bounding box of the ball and the bounding
+// box of the brick.
+func checkForBallBrickCollision(b *brick) bool {
+	if ballX+float64(ballW) < float64(b.x) {
+		return false
+	}
+	if ballX > float64(b.x+b.w) {
+		return false
+	}
+	if ballY+float64(ballH) < float64(b.y) {
+		return false
+	}
+	if ballY > float64(b.y+b.h) {
+		return false
+	}
+	return true
+}
+
+// ReflectBallFromBrick bounces the ball off a brick. If the ball hit the side
+// of the brick it goes back the way it came, and if it hit the top or the
+// bottom of the brick it goes back up or down.
+func reflectBallFromBrick(b *brick) {
+	// work out how far the ball has gone into the brick from each side.
+	// The smallest overlap tells us which side of the brick the ball hit.
+	var overlapLeft = ballX + float64(ballW) - float64(b.x)
+	var overlapRight = float64(b.x+b.w) - ballX
+	var overlapTop = ballY + float64(ballH) - float64(b.y)
+	var overlapBottom = float64(b.y+b.h) - ballY
+
+	var overlapX = math.Min(overlapLeft, overlapRight)
+	var overlapY = math.Min(overlapTop, overlapBottom)
+
+	if overlapX < overlapY {
+		// we hit the left or the right side, so move the ball out of the
+		// brick and send it back the way it came
+		if overlapLeft < overlapRight {
+			ballX = float64(b.x - ballW)
+		} else {
+			ballX = float64(b.x + b.w)
+		}
+		ballDirX = ballDirX * -1
+	} else {
+		// we hit the top or the bottom
+		if overlapTop < overlapBottom {
+			ballY = float64(b.y - ballH)
+		} else {
+			ballY = float64(b.y + b.h)
+		}
+		ballDirY = ballDirY * -1
+	}
+}
+
+// RenderBricks draws every brick that has not been broken yet, the way the
+// theme says (see theme.go).
+func renderBricks() {
+	var picture *themePicture
+	picture = &currentTheme().Bricks
+	var i int
+	for i = 0; i < len(bricks); i++ {
+		if bricks[i].broken == true {
+			continue
+		}
+		var dst sdl.Rect
+		dst.X = int32(bricks[i].x)
+		dst.Y = int32(bricks[i].y)
+		dst.W = int32(bricks[i].w)
+		dst.H = int32(bricks[i].h)
+		renderThemePicture(picture, dst, untinted)
+	}
+}
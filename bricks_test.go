@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBrickLayout(t *testing.T) {
+	var rows []string
+	rows = parseBrickLayout(strings.NewReader("// a comment\n\n##.#  \r\n#..#\n"))
+	if reflect.DeepEqual(rows, []string{"##.#", "#..#"}) == false {
+		t.Errorf("read the rows %q", rows)
+	}
+}
+
+func TestLoadBrickLayout(t *testing.T) {
+	setUpTestMatch(t)
+	// a layout file
+	var filename string
+	filename = filepath.Join(t.TempDir(), "wall.txt")
+	writeTestFile(t, filename, []byte("###\n#.#\n"))
+	var rows []string
+	rows = loadBrickLayout(filename)
+	if reflect.DeepEqual(rows, []string{"###", "#.#"}) == false {
+		t.Errorf("read the rows %q", rows)
+	}
+	// a layout built into the game
+	rows = loadBrickLayout("diamond.txt")
+	if len(rows) == 0 {
+		t.Error("the diamond layout has no rows")
+	}
+	// a layout that isn't anywhere
+	defer func() {
+		if recover() == nil {
+			t.Error("loaded a layout that doesn't exist")
+		}
+	}()
+	loadBrickLayout(filepath.Join(t.TempDir(), "nothing.txt"))
+}
+
+func TestBuildBricks(t *testing.T) {
+	setUpTestMatch(t)
+	buildBricks([]string{"#.#", "###"})
+	if len(bricks) != 5 || bricksLeft != 5 {
+		t.Fatalf("built %d bricks, %d left", len(bricks), bricksLeft)
+	}
+	// the wall is in the middle of the field
+	var left, right int
+	left = bricks[0].x
+	right = bricks[1].x + bricks[1].w
+	if left != windowWidth-right {
+		t.Errorf("the wall runs from %d to %d", left, right)
+	}
+	if bricks[1].x != left+2*(BrickWidth+BrickGap) || bricks[2].y != bricks[0].y+BrickHeight+BrickGap {
+		t.Errorf("the gap in the wall is in the wrong place: %+v", bricks)
+	}
+}
+
+// TestBrickScoring checks breaking a brick scores for whoever hit the ball
+// last, and that the game ends on reaching the target.
+func TestBrickScoring(t *testing.T) {
+	setUpTestMatch(t)
+	buildBricks([]string{"##"})
+	myTargetScore = 1
+	// put the ball on the first brick
+	ballX = float64(bricks[0].x)
+	ballY = float64(bricks[0].y)
+	lastHitBy = Player
+	checkForBallBrickCollisions()
+	if bricks[0].broken == false || bricksLeft != 1 {
+		t.Fatal("the brick the ball hit didn't break")
+	}
+	if myScore != 1 || computersScore != 0 {
+		t.Errorf("the score is %d-%d", myScore, computersScore)
+	}
+	if gameOver == false {
+		t.Error("the game didn't end on reaching the target")
+	}
+
+	// nobody scores if nobody has hit the ball, but breaking the last brick
+	// ends the game
+	gameOver = false
+	ballX = float64(bricks[1].x)
+	ballY = float64(bricks[1].y)
+	lastHitBy = NoOne
+	checkForBallBrickCollisions()
+	if myScore != 1 || computersScore != 0 {
+		t.Errorf("the score is %d-%d", myScore, computersScore)
+	}
+	if bricksLeft != 0 || gameOver == false {
+		t.Error("the game didn't end when the wall was gone")
+	}
+}
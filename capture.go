@@ -0,0 +1,419 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	// pong.go already has a variable called image, so the image package
+	// needs a different name here
+	goimage "image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Capturing the game ----
+//
+// The game can copy what it draws on the screen into an animated GIF, or into
+// a folder of numbered PNG pictures, so that the best bits of a match can be
+// shared. Press F9 to start capturing and F9 again to stop. A replay can also
+// be turned into a GIF without opening a window at all:
+//
+//	pong replay match.replay -export match.gif
+//
+// The pictures are read back from the renderer with ReadPixels, just after
+// the game has been drawn and before it is shown on the screen.
+
+// The formats the game can capture to.
+const GifCapture = "gif"
+const PngCapture = "png"
+
+// Capturing 60 pictures a second makes enormous files, so only one picture
+// every CaptureFrameStep game updates is kept. 3 gives 20 pictures a second.
+const CaptureFrameStep = 3
+
+// GIF delays are measured in hundredths of a second.
+const GifFrameDelay = CaptureFrameStep * 100 / UpdatesPerSecond
+
+// GIF pictures are shrunk to half the size of the window.
+const GifScale = 2
+
+// A GIF is built in memory, so it can't be too long. PNG pictures are saved
+// as they are captured, so they can go on as long as you like.
+const MaxGifSeconds = 20
+const MaxGifFrames = MaxGifSeconds * UpdatesPerSecond / CaptureFrameStep
+
+// How long a message about a capture stays on the screen.
+const NoticeFrames = 2 * UpdatesPerSecond
+
+// The settings from the command line.
+var captureDirectory string
+var captureFormat string
+var captureFromStart bool
+var exportFilename string
+
+// The capturing flag is true while the game is being captured.
+var capturing bool
+
+// How many game updates have gone by since the last picture was captured.
+var captureTick int
+
+// The GIF being captured.
+var gifFrames []*goimage.Paletted
+var gifFilename string
+
+// Every colour seen so far, and the colour in the GIF palette that is
+// closest to it. Pong only uses a few colours, so remembering them is much
+// faster than searching the palette for every pixel.
+var gifColours map[color.RGBA]uint8
+
+// The PNG pictures are saved by another goroutine, so saving them doesn't
+// make the game stutter. The pictures are sent to it on pngFrames, and it
+// closes pngSaved when it has finished.
+var pngFrames chan *goimage.RGBA
+var pngSaved chan bool
+var pngDirectory string
+
+// How many PNG pictures were left out because the disk couldn't keep up.
+var pngDropped int
+
+// A short message shown at the bottom of the screen, and how many more game
+// updates it stays there for.
+var noticeText string
+var noticeFramesLeft int
+
+// AddCaptureFlags adds the command line flags for capturing the game.
+func addCaptureFlags() {
+	flag.StringVar(&captureDirectory, "capture-dir", "captures", "the folder captures are saved in")
+	flag.StringVar(&captureFormat, "capture-format", GifCapture, "what F9 captures to: gif or png")
+	flag.BoolVar(&captureFromStart, "capture", false, "start capturing as soon as the game starts")
+	flag.StringVar(&exportFilename, "export", "", "turn the replay into a GIF, or a folder of PNG pictures, without opening a window")
+}
+
+// CheckCaptureFlags makes sure the capture settings make sense.
+func checkCaptureFlags() error {
+	if captureFormat != GifCapture && captureFormat != PngCapture {
+		return badSetting("capture-format", "unknown capture format "+captureFormat)
+	}
+	if exportFilename != "" && replayFilename == "" {
+		return badSetting("export", "only a replay can be exported, for example: pong replay match.replay -export match.gif")
+	}
+	return nil
+}
+
+// StartCaptureFromCommandLine starts capturing if the command line asked
+// for it.
+func startCaptureFromCommandLine() {
+	if captureFromStart == true {
+		startCapture(captureFormat, captureFilename(captureFormat))
+	}
+}
+
+// CaptureFilename makes up a name for a new capture from the date and time.
+// A PNG capture is a folder, so it doesn't have an extension.
+func captureFilename(format string) string {
+	var name string
+	name = time.Now().Format("pong-2006-01-02-150405")
+	if format == GifCapture {
+		name = name + ".gif"
+	}
+	return filepath.Join(captureDirectory, name)
+}
+
+// HandleCaptureEvent starts or stops capturing when F9 is pressed. It returns
+// true if it used the event.
+func handleCaptureEvent(event sdl.Event) bool {
+	if isKeyDownEvent(event) == false {
+		return false
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	if keyDownEvt.Keysym.Sym != sdl.K_F9 {
+		return false
+	}
+	if capturing == true {
+		stopCapture()
+	} else {
+		startCapture(captureFormat, captureFilename(captureFormat))
+	}
+	return true
+}
+
+// StartCapture starts capturing to a GIF file, or to a folder of PNG files.
+func startCapture(format string, filename string) {
+	// the first picture is taken after the next update
+	captureTick = CaptureFrameStep - 1
+	if format == GifCapture {
+		gifFrames = nil
+		gifFilename = filename
+		gifColours = make(map[color.RGBA]uint8)
+	} else {
+		var err error
+		err = os.MkdirAll(filename, 0755)
+		if err != nil {
+			fmt.Print("Failed to make the capture folder: ")
+			fmt.Println(err)
+			showNotice("COULD NOT START CAPTURING")
+			return
+		}
+		pngDirectory = filename
+		pngDropped = 0
+		pngFrames = make(chan *goimage.RGBA, UpdatesPerSecond)
+		pngSaved = make(chan bool)
+		go savePngFrames(pngDirectory, pngFrames, pngSaved)
+	}
+	captureFormat = format
+	capturing = true
+}
+
+// StopCapture stops capturing and saves what has been captured.
+func stopCapture() {
+	if capturing == false {
+		return
+	}
+	capturing = false
+	if captureFormat == PngCapture {
+		// wait for the last pictures to be saved
+		close(pngFrames)
+		<-pngSaved
+		fmt.Println("Saved the capture to " + pngDirectory)
+		if pngDropped > 0 {
+			fmt.Printf("%d pictures were left out because they couldn't be saved fast enough\n", pngDropped)
+			showNotice(fmt.Sprintf("SAVED %s - %d PICTURES LEFT OUT", strings.ToUpper(pngDirectory), pngDropped))
+			return
+		}
+		showNotice("SAVED " + strings.ToUpper(pngDirectory))
+		return
+	}
+	var err error
+	err = saveGif(gifFilename, gifFrames)
+	gifFrames = nil
+	if err != nil {
+		fmt.Print("Failed to save the capture: ")
+		fmt.Println(err)
+		showNotice("COULD NOT SAVE THE CAPTURE")
+		return
+	}
+	fmt.Println("Saved the capture to " + gifFilename)
+	showNotice("SAVED " + strings.ToUpper(gifFilename))
+}
+
+// CaptureFrame copies the picture the renderer has just drawn into the
+// capture. It must be called after the game has been drawn, but before
+// renderer.Present.
+func captureFrame() {
+	if capturing == false || captureTick < CaptureFrameStep {
+		return
+	}
+	var picture *goimage.RGBA
+	picture = readRenderer()
+	if picture == nil {
+		return
+	}
+	// if the frames are drawn slowly there can be more than one picture's
+	// worth of updates since the last frame, so the picture is used more
+	// than once to keep the capture in time
+	if captureFormat == PngCapture {
+		for captureTick >= CaptureFrameStep {
+			sendPngFrame(picture)
+			captureTick = captureTick - CaptureFrameStep
+		}
+		return
+	}
+	var shrunk *goimage.Paletted
+	shrunk = shrinkForGif(picture)
+	for captureTick >= CaptureFrameStep && capturing == true {
+		gifFrames = append(gifFrames, shrunk)
+		captureTick = captureTick - CaptureFrameStep
+		if len(gifFrames) >= MaxGifFrames {
+			stopCapture()
+		}
+	}
+}
+
+// SendPngFrame hands a picture to the goroutine that saves the PNG pictures.
+// If the disk is too slow and the goroutine has fallen behind, waiting for it
+// would make the game stutter, so the picture is left out and counted
+// instead. When a replay is exported nobody is playing, so every picture is
+// kept.
+func sendPngFrame(picture *goimage.RGBA) {
+	if exportFilename != "" {
+		pngFrames <- picture
+		return
+	}
+	select {
+	case pngFrames <- picture:
+	default:
+		pngDropped = pngDropped + 1
+	}
+}
+
+// CountCaptureUpdate counts one game update, so the pictures are captured,
+// and the messages about captures stay on the screen, for the same time
+// whatever the frame rate is.
+func countCaptureUpdate() {
+	if capturing == true {
+		captureTick = captureTick + 1
+	}
+	if noticeFramesLeft > 0 {
+		noticeFramesLeft = noticeFramesLeft - 1
+	}
+}
+
+// ReadRenderer reads the pixels the renderer has drawn on the playing field
+// into a picture.
+func readRenderer() *goimage.RGBA {
+	// the game is drawn on the playing field, so the picture is always the
+	// size of the playing field, whatever size the window is
+	var picture *goimage.RGBA
+	picture = goimage.NewRGBA(goimage.Rect(0, 0, windowWidth, windowHeight))
+	var err error
+	// ReadPixels is part of the C library, so it needs to be told where the
+	// picture's pixels are in memory. unsafe.Pointer is how Go does that.
+	// ABGR8888 puts the red, green, blue and alpha bytes in the same order
+	// as Go's RGBA pictures.
+	err = renderer.ReadPixels(nil, sdl.PIXELFORMAT_ABGR8888, unsafe.Pointer(&picture.Pix[0]), picture.Stride)
+	if err != nil {
+		fmt.Print("Failed to read the screen: ")
+		fmt.Println(err)
+		return nil
+	}
+	return picture
+}
+
+// ShrinkForGif makes a smaller copy of a picture for a GIF. A GIF can only
+// have 256 colours, so every pixel is changed to the closest web safe colour.
+func shrinkForGif(picture *goimage.RGBA) *goimage.Paletted {
+	var w, h int
+	w = picture.Bounds().Dx() / GifScale
+	h = picture.Bounds().Dy() / GifScale
+	var small *goimage.Paletted
+	small = goimage.NewPaletted(goimage.Rect(0, 0, w, h), palette.WebSafe)
+	var x, y int
+	for y = 0; y < h; y++ {
+		for x = 0; x < w; x++ {
+			var c color.RGBA
+			c = picture.RGBAAt(x*GifScale, y*GifScale)
+			c.A = 255
+			var index uint8
+			var found bool
+			index, found = gifColours[c]
+			if found == false {
+				index = uint8(small.Palette.Index(c))
+				gifColours[c] = index
+			}
+			small.SetColorIndex(x, y, index)
+		}
+	}
+	return small
+}
+
+// SaveGif saves the captured pictures as an animated GIF.
+func saveGif(filename string, frames []*goimage.Paletted) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("nothing was captured")
+	}
+	var err error
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	var animation gif.GIF
+	animation.Image = frames
+	var i int
+	for i = 0; i < len(frames); i++ {
+		animation.Delay = append(animation.Delay, GifFrameDelay)
+	}
+	var file *os.File
+	file, err = os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gif.EncodeAll(file, &animation)
+}
+
+// SavePngFrames runs in its own goroutine. It saves each picture it is sent
+// as a numbered PNG file, until the channel is closed.
+func savePngFrames(directory string, frames chan *goimage.RGBA, saved chan bool) {
+	// fast compression, so the saving keeps up with the game
+	var encoder png.Encoder
+	encoder.CompressionLevel = png.BestSpeed
+	var number int
+	var picture *goimage.RGBA
+	for picture = range frames {
+		number = number + 1
+		var filename string
+		filename = filepath.Join(directory, fmt.Sprintf("frame-%05d.png", number))
+		var err error
+		err = savePng(&encoder, filename, picture)
+		if err != nil {
+			fmt.Print("Failed to save a captured picture: ")
+			fmt.Println(err)
+		}
+	}
+	close(saved)
+}
+
+// SavePng saves one picture as a PNG file.
+func savePng(encoder *png.Encoder, filename string, picture goimage.Image) error {
+	var file *os.File
+	var err error
+	file, err = os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = encoder.Encode(file, picture)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ExportReplay plays the whole replay as fast as it can, drawing each frame
+// into the hidden window and capturing it.
+func exportReplay() {
+	var format string
+	format = PngCapture
+	if strings.HasSuffix(strings.ToLower(exportFilename), ".gif") {
+		format = GifCapture
+	}
+	startCapture(format, exportFilename)
+	for playbackFrame < len(playback.Inputs) && capturing == true {
+		playRecordedFrame()
+		countCaptureUpdate()
+		renderer.Clear()
+		renderGame()
+		captureFrame()
+	}
+	if capturing == true {
+		stopCapture()
+	} else if format == GifCapture {
+		fmt.Printf("The GIF was cut short after %d seconds\n", MaxGifSeconds)
+	}
+}
+
+// ShowNotice shows a short message at the bottom of the screen.
+func showNotice(text string) {
+	noticeText = text
+	noticeFramesLeft = NoticeFrames
+}
+
+// RenderCaptureStatus shows a red REC while the game is being captured, and
+// any message about a capture.
+func renderCaptureStatus() {
+	if capturing == true {
+		renderText("REC", 16, 16, 3, 255, 0, 0)
+	}
+	if noticeFramesLeft > 0 {
+		renderTextCentred(noticeText, windowWidth/2, windowHeight-60, 2, 255, 255, 0)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	goimage "image"
+	"testing"
+)
+
+// TestSendPngFrame checks a picture is left out, instead of making the game
+// wait, when the pictures can't be saved fast enough.
+func TestSendPngFrame(t *testing.T) {
+	exportFilename = ""
+	pngDropped = 0
+	pngFrames = make(chan *goimage.RGBA, 1)
+	var picture *goimage.RGBA
+	picture = goimage.NewRGBA(goimage.Rect(0, 0, 1, 1))
+	sendPngFrame(picture)
+	sendPngFrame(picture)
+	if len(pngFrames) != 1 || pngDropped != 1 {
+		t.Errorf("%d pictures were sent and %d were left out", len(pngFrames), pngDropped)
+	}
+}
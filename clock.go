@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ---- Timed matches ----
+//
+// In a timed match the game is over when the time runs out, even if nobody
+// has reached their target score. If the scores are the same when the time runs
+// out we play a golden point - sudden death. Whoever scores next wins.
+
+// The game updates its state this many times every second. We use this to
+// turn the number of updates into seconds on the clock.
+const UpdatesPerSecond = 60
+
+// How long a timed match lasts. If the time limit is zero the match is not
+// timed and it only ends when someone reaches their target score.
+var timeLimit time.Duration
+
+// The number of times the game state has been updated since the match started.
+// The game state is not updated while the game is paused, so the clock stops
+// when the game is paused.
+var matchUpdates int
+
+// The sudden death flag is true when the time has run out and the scores
+// were the same. The next point wins the match.
+var suddenDeath bool
+
+// InitialiseClock starts the clock for a new match.
+func initialiseClock() {
+	matchUpdates = 0
+	suddenDeath = false
+}
+
+// IsTimedMatch returns true if the match has a time limit.
+func isTimedMatch() bool {
+	return timeLimit > 0
+}
+
+// UpdateClock moves the clock on by one update and ends the match when the
+// time runs out. UpdateClock must be called once every time the game state is
+// updated.
+func updateClock() {
+	if isTimedMatch() == false {
+		return
+	}
+	// once we are playing the golden point the clock has stopped
+	if suddenDeath == true {
+		return
+	}
+	matchUpdates = matchUpdates + 1
+	if timeLeft() > 0 {
+		return
+	}
+	// the time has run out. If someone is winning the match is over,
+	// otherwise we play a golden point.
+	if myScore != computersScore {
+		gameOver = true
+	} else {
+		suddenDeath = true
+	}
+}
+
+// CheckForGoldenPoint ends the match if someone has scored the golden point.
+func checkForGoldenPoint() {
+	if suddenDeath == true && myScore != computersScore {
+		gameOver = true
+	}
+}
+
+// TimeLeft works out how much time is left in the match.
+func timeLeft() time.Duration {
+	var played time.Duration
+	played = time.Duration(matchUpdates) * time.Second / UpdatesPerSecond
+	if played > timeLimit {
+		return 0
+	}
+	return timeLimit - played
+}
+
+// FormatClock turns a time into the minutes and seconds shown on the clock,
+// for example 2:59. We round up, so the clock only shows 0:00 when the time
+// has really run out.
+func formatClock(t time.Duration) string {
+	var seconds int
+	seconds = int((t + time.Second - 1) / time.Second)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// RenderClock draws the time left at the top of the screen. During sudden
+// death it tells the players that the next point wins.
+func renderClock() {
+	if isTimedMatch() == false {
+		return
+	}
+	if suddenDeath == true {
+		renderTextCentred("GOLDEN POINT", windowWidth/2, 16, 4, 255, 255, 0)
+		return
+	}
+	renderTextCentred(formatClock(timeLeft()), windowWidth/2, 16, 4, 255, 255, 255)
+}
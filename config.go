@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Settings ----
+//
+// Every setting in the game can come from four places. Each place overrides
+// the ones before it:
+//
+//  1. the default that is built into the game
+//  2. the settings file, config.json in the player's settings folder
+//  3. environment variables, for example PONG_BALL_SPEED=700
+//  4. the command line, for example -ball-speed 700
+//
+// A setting has the same name everywhere. The settings file looks like this
+//
+//	{
+//	  "mode": "breakout",
+//	  "ball-speed": 700,
+//	  "capture": true
+//	}
+//
+// and the environment variable for a setting is its name in capital letters,
+// with _ instead of -, and PONG_ in front.
+//
+// Every setting is a command line flag, so pong -help lists them all.
+
+// The name of the settings file in the player's settings folder.
+const ConfigFilename = "config.json"
+
+// The limits on the settings added here. The ball can't go so fast it jumps
+// over a bat in one frame.
+const MinimumFramesPerSecond = 10
+const MaximumFramesPerSecond = 240
+const DefaultBallSpeed = 550
+const MinimumBallSpeed = 100
+const MaximumBallSpeed = 1200
+const DefaultComputerSpeed = 350
+const MinimumComputerSpeed = 50
+const MaximumComputerSpeed = 2000
+
+// The settings file named on the command line. If it is empty the file in
+// the player's settings folder is used, if there is one.
+var configFilename string
+
+// Where each setting that isn't the default came from, so a bad setting can
+// be found. For example settingSources["ball-speed"] might be
+// "PONG_BALL_SPEED".
+var settingSources map[string]string
+
+// The balls speed in pixels per second. This must be the same on every
+// computer playing a match, so it is sent with the network settings.
+var ballSpeed int
+
+// How fast the computer moves its bat, in pixels per second, before the
+// difficulty and the handicap change it.
+var computerSpeed int
+
+// The score a player needs to win, unless a handicap changes it.
+var winningScore int
+
+// How many frames are drawn every second. The game state is always updated
+// UpdatesPerSecond times a second, so this doesn't change how fast the game
+// goes (see gameMainLoop).
+var framesPerSecond int
+
+// The keys that move my bat up and down, as well as the cursor keys.
+var upKeyName string
+var downKeyName string
+var upKey sdl.Keycode
+var downKey sdl.Keycode
+
+// AddConfigFlags adds the command line flags for the settings file and for
+// the settings that used to be built into the game.
+func addConfigFlags() {
+	flag.StringVar(&configFilename, "config", "", "the settings file to use instead of "+ConfigFilename+" in the settings folder")
+	flag.IntVar(&framesPerSecond, "fps", 60, "how many frames are drawn every second. The game goes at the same speed whatever this is")
+	flag.IntVar(&ballSpeed, "ball-speed", DefaultBallSpeed, "the speed of the ball in pixels per second")
+	flag.IntVar(&computerSpeed, "computer-speed", DefaultComputerSpeed, "how fast the computer moves its bat, in pixels per second")
+	flag.IntVar(&winningScore, "winning-score", HighestScore, "the score a player needs to win the match")
+	flag.StringVar(&upKeyName, "up-key", "Up", "another key that moves your bat up, for example I")
+	flag.StringVar(&downKeyName, "down-key", "Down", "another key that moves your bat down, for example K")
+}
+
+// LoadSettings fills in the settings that weren't given on the command line
+// from the environment variables and then from the settings file. It must be
+// called after flag.Parse.
+func loadSettings() error {
+	settingSources = make(map[string]string)
+	// flag.Visit calls the function for every flag that was set on the
+	// command line. Nothing else can change these.
+	flag.Visit(func(f *flag.Flag) {
+		settingSources[f.Name] = "the command line"
+	})
+	var err error
+	err = loadEnvironmentSettings()
+	if err != nil {
+		return err
+	}
+	var filename string
+	filename = configFilename
+	if filename == "" {
+		filename = userFilePath(ConfigFilename)
+	}
+	return loadSettingsFile(filename, configFilename != "")
+}
+
+// LoadEnvironmentSettings uses the PONG_ environment variables.
+func loadEnvironmentSettings() error {
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		var name, value string
+		var found bool
+		name = environmentName(f.Name)
+		value, found = os.LookupEnv(name)
+		if found == true && err == nil {
+			err = useSetting(f.Name, value, name)
+		}
+	})
+	return err
+}
+
+// EnvironmentName works out the environment variable for a setting. For
+// example ball-speed is PONG_BALL_SPEED.
+func environmentName(name string) string {
+	return "PONG_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// LoadSettingsFile uses the settings in a settings file. It doesn't matter if
+// the file in the settings folder isn't there, but a file named on the
+// command line must be there.
+func loadSettingsFile(filename string, mustExist bool) error {
+	var data []byte
+	var err error
+	data, err = os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) == true && mustExist == false {
+			return nil
+		}
+		return err
+	}
+	// UseNumber keeps the numbers as they were typed, so 700 doesn't
+	// become 700.0
+	var settings map[string]interface{}
+	var decoder *json.Decoder
+	decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&settings)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	// go through the settings in order, so the same mistake is always
+	// reported first
+	var names []string
+	var name string
+	for name = range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var source string
+	source = "the settings file " + filepath.Clean(filename)
+	for _, name = range names {
+		var value string
+		switch v := settings[name].(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return errors.New(source + " sets " + name + " to something that isn't a word, a number, true or false")
+		}
+		if flag.Lookup(name) == nil {
+			return errors.New(source + " sets " + name + ", but there is no setting called " + name)
+		}
+		err = useSetting(name, value, source)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseSetting changes a setting, and remembers where the new value came from.
+// A setting that was already set somewhere that wins, like the command line,
+// isn't changed.
+func useSetting(name string, value string, source string) error {
+	if settingSources[name] != "" {
+		return nil
+	}
+	var err error
+	err = flag.Set(name, value)
+	if err != nil {
+		return fmt.Errorf("%s sets %s to %q, which isn't right: %v", source, name, value, err)
+	}
+	settingSources[name] = source
+	return nil
+}
+
+// BadSetting makes the error for a setting that doesn't make sense. It says
+// where the setting came from, so the player knows where to change it.
+func badSetting(name string, problem string) error {
+	var source string
+	source = settingSources[name]
+	if source == "" {
+		return errors.New(name + ": " + problem)
+	}
+	return errors.New(name + ": " + problem + " (set by " + source + ")")
+}
+
+// CheckConfigFlags makes sure the settings added here make sense.
+func checkConfigFlags() error {
+	if framesPerSecond < MinimumFramesPerSecond || framesPerSecond > MaximumFramesPerSecond {
+		return badSetting("fps", fmt.Sprintf("the game must draw between %d and %d frames a second", MinimumFramesPerSecond, MaximumFramesPerSecond))
+	}
+	if ballSpeed < MinimumBallSpeed || ballSpeed > MaximumBallSpeed {
+		return badSetting("ball-speed", fmt.Sprintf("the ball speed must be between %d and %d", MinimumBallSpeed, MaximumBallSpeed))
+	}
+	if computerSpeed < MinimumComputerSpeed || computerSpeed > MaximumComputerSpeed {
+		return badSetting("computer-speed", fmt.Sprintf("the computer speed must be between %d and %d", MinimumComputerSpeed, MaximumComputerSpeed))
+	}
+	if winningScore < 1 || winningScore > HighestScore {
+		// we only have graphics for the scores 0 to 11
+		return badSetting("winning-score", fmt.Sprintf("the winning score must be between 1 and %d", HighestScore))
+	}
+	upKey = sdl.GetKeyFromName(upKeyName)
+	if upKey == sdl.K_UNKNOWN {
+		return badSetting("up-key", "there is no key called "+upKeyName)
+	}
+	downKey = sdl.GetKeyFromName(downKeyName)
+	if downKey == sdl.K_UNKNOWN {
+		return badSetting("down-key", "there is no key called "+downKeyName)
+	}
+	return nil
+}
+
+// UseConfiguredKeys changes the keys chosen with -up-key and -down-key into
+// the up and down cursor keys. When two people are playing on one computer
+// the keys are always W and S and the cursor keys, so nothing is changed.
+func useConfiguredKeys(event sdl.Event) sdl.Event {
+	if twoPlayers == true || isKeyDownEvent(event) == false {
+		return event
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	var changed sdl.KeyDownEvent
+	changed = *keyDownEvt
+	switch keyDownEvt.Keysym.Sym {
+	case upKey:
+		changed.Keysym.Sym = sdl.K_UP
+		return &changed
+	case downKey:
+		changed.Keysym.Sym = sdl.K_DOWN
+		return &changed
+	}
+	return event
+}
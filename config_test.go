@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+)
+
+// UseTestFlags gives the test its own set of the settings in config.go, so
+// the settings can be loaded more than once.
+func useTestFlags(t *testing.T) {
+	var saved *flag.FlagSet
+	saved = flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet("pong", flag.ContinueOnError)
+	t.Cleanup(func() { flag.CommandLine = saved })
+	addConfigFlags()
+	settingSources = make(map[string]string)
+}
+
+func TestLoadSettingsFile(t *testing.T) {
+	var tests = []struct {
+		name     string
+		settings string
+		ok       bool
+	}{
+		{"no settings", `{}`, true},
+		{"numbers, words and true or false", `{"ball-speed": 700, "up-key": "I", "fps": 30}`, true},
+		{"a number in quotes", `{"ball-speed": "700"}`, true},
+		{"not JSON", `{"ball-speed": `, false},
+		{"an unknown setting", `{"ball-sped": 700}`, false},
+		{"a list", `{"ball-speed": [700]}`, false},
+		{"a word for a number", `{"ball-speed": "fast"}`, false},
+		{"a fraction for a whole number", `{"ball-speed": 700.5}`, false},
+	}
+	var filename string
+	filename = filepath.Join(t.TempDir(), ConfigFilename)
+	var test struct {
+		name     string
+		settings string
+		ok       bool
+	}
+	var err error
+	for _, test = range tests {
+		useTestFlags(t)
+		writeTestFile(t, filename, []byte(test.settings))
+		err = loadSettingsFile(filename, true)
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: loaded settings it should have turned away", test.name)
+		}
+	}
+}
+
+// TestSettingsFileValues checks the settings file changes the settings, but
+// not the ones that were set somewhere that wins.
+func TestSettingsFileValues(t *testing.T) {
+	useTestFlags(t)
+	var filename string
+	filename = filepath.Join(t.TempDir(), ConfigFilename)
+	writeTestFile(t, filename, []byte(`{"ball-speed": 700, "computer-speed": 500, "up-key": "I"}`))
+	settingSources["computer-speed"] = "the command line"
+	var err error
+	err = loadSettingsFile(filename, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ballSpeed != 700 || upKeyName != "I" {
+		t.Errorf("the ball speed is %d and the up key is %s", ballSpeed, upKeyName)
+	}
+	if computerSpeed != 350 {
+		t.Errorf("the settings file changed the computer speed to %d", computerSpeed)
+	}
+	if settingSources["ball-speed"] != "the settings file "+filename {
+		t.Errorf("the ball speed came from %q", settingSources["ball-speed"])
+	}
+}
+
+// TestMissingSettingsFile checks a missing settings file only matters if it
+// was named on the command line.
+func TestMissingSettingsFile(t *testing.T) {
+	useTestFlags(t)
+	var filename string
+	filename = filepath.Join(t.TempDir(), ConfigFilename)
+	var err error
+	err = loadSettingsFile(filename, false)
+	if err != nil {
+		t.Errorf("a missing settings file in the settings folder: %v", err)
+	}
+	err = loadSettingsFile(filename, true)
+	if err == nil {
+		t.Error("a missing settings file named on the command line wasn't noticed")
+	}
+}
+
+// TestEnvironmentSettings checks the PONG_ environment variables beat the
+// settings file.
+func TestEnvironmentSettings(t *testing.T) {
+	useTestFlags(t)
+	t.Setenv("PONG_BALL_SPEED", "800")
+	var filename string
+	filename = filepath.Join(t.TempDir(), ConfigFilename)
+	writeTestFile(t, filename, []byte(`{"ball-speed": 700}`))
+	var err error
+	err = loadEnvironmentSettings()
+	if err == nil {
+		err = loadSettingsFile(filename, true)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ballSpeed != 800 || settingSources["ball-speed"] != "PONG_BALL_SPEED" {
+		t.Errorf("the ball speed is %d, from %q", ballSpeed, settingSources["ball-speed"])
+	}
+}
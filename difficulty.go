@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// ---- How hard the computer is to beat ----
+//
+// The difficulty changes how fast the computer can move its bat when it
+// chases the ball. An easy computer is slow, so it often can't get to the
+// ball in time. A hard computer is fast, so it hardly ever misses.
+
+// The difficulties
+const EasyDifficulty = 0
+const NormalDifficulty = 1
+const HardDifficulty = 2
+
+// The names of the difficulties, in the same order as the numbers above.
+var difficultyNames = [...]string{"EASY", "NORMAL", "HARD"}
+
+// How fast the computer's bat moves at each difficulty, as a percentage of
+// the normal speed.
+var difficultySpeeds = [...]float64{60, 100, 150}
+
+// The difficulty of the computer player.
+var difficulty int
+
+// The difficulty typed on the command line.
+var difficultyName string
+
+// AddDifficultyFlags adds the command line flag that sets the difficulty.
+func addDifficultyFlags() {
+	flag.StringVar(&difficultyName, "difficulty", "normal", "how hard the computer is to beat: easy, normal or hard")
+}
+
+// CheckDifficultyFlag works out which difficulty the settings asked for.
+func checkDifficultyFlag() error {
+	var i int
+	for i = 0; i < len(difficultyNames); i++ {
+		if difficultyNames[i] == strings.ToUpper(difficultyName) {
+			difficulty = i
+			return nil
+		}
+	}
+	return badSetting("difficulty", "unknown difficulty "+difficultyName)
+}
+
+// NextDifficulty changes the difficulty to the next one, or the one before if
+// step is -1, going round to the other end at the ends.
+func nextDifficulty(step int) {
+	difficulty = (difficulty + step + len(difficultyNames)) % len(difficultyNames)
+}
+
+// ComputersDifficultySpeed is how fast the computer's bat moves when the
+// computer is playing, as a fraction of its speed when a person moves it.
+func computersDifficultySpeed() float64 {
+	return difficultySpeeds[difficulty] / 100
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"math/rand"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Effects ----
+//
+// Effects make the game more exciting to watch. Sparks fly when the ball hits
+// a bat or a wall, there is a burst when a point is scored, and confetti falls
+// when the match is over.
+//
+// Each spark or piece of confetti is a particle: a small square that flies
+// off in a straight line, falls a little, and shrinks until it disappears.
+// There can only be MaxParticles at once, so a slow computer is never asked
+// to draw too many. The -effects setting turns them off altogether.
+//
+// Effects only change how the game looks, never what happens in it. They use
+// their own random numbers, so the serve random number generator that both
+// computers in a network game share isn't touched.
+
+// The most particles there can be at once. New particles are left out until
+// some of the old ones have gone.
+const MaxParticles = 500
+
+// How many particles each effect makes.
+const SparkParticles = 12
+const GoalParticles = 60
+const ConfettiParticles = 200
+
+// How hard particles are pulled down, in pixels per second per second.
+const ParticleGravity = 400
+
+// A particle is one spark or piece of confetti. Its speed is in pixels per
+// second, and its life is counted in frames.
+type particle struct {
+	x, y           float64
+	xSpeed, ySpeed float64
+	size           int
+	life, lifetime int
+	r, g, b        uint8
+}
+
+// The effects flag is false if effects have been turned off.
+var effects bool
+
+// The particles on the screen.
+var particles []particle
+
+// Whether the end of the match was on the screen last frame, so we can tell
+// when it appears.
+var wasShowingGameOver bool
+
+// AddEffectsFlags adds the command line flag that turns the effects off.
+func addEffectsFlags() {
+	flag.BoolVar(&effects, "effects", true, "draw sparks, bursts and confetti. Turn them off on slow computers")
+}
+
+// EffectsWanted is true if effects should be made now. Rollback plays frames
+// again when it guesses wrong (see rollback.go), and those frames have
+// already had their effects.
+func effectsWanted() bool {
+	return effects == true && resimulating == false
+}
+
+// AddParticle adds a particle, if there is room for it.
+func addParticle(p particle) {
+	if len(particles) >= MaxParticles {
+		return
+	}
+	particles = append(particles, p)
+}
+
+// AddParticles adds count particles at x and y, flying in every direction
+// at up to speed pixels per second, for up to seconds.
+func addParticles(count int, x, y, speed, seconds float64, r, g, b uint8) {
+	var i int
+	for i = 0; i < count; i++ {
+		var p particle
+		var angle, s float64
+		angle = rand.Float64() * 2 * math.Pi
+		// a spread of speeds looks more like an explosion
+		s = speed * (0.3 + 0.7*rand.Float64())
+		p.x = x
+		p.y = y
+		p.xSpeed = math.Cos(angle) * s
+		p.ySpeed = math.Sin(angle) * s
+		p.size = 3 + rand.Intn(3)
+		p.lifetime = int(seconds * float64(framesPerSecond) * (0.5 + 0.5*rand.Float64()))
+		if p.lifetime < 1 {
+			p.lifetime = 1
+		}
+		p.life = p.lifetime
+		p.r, p.g, p.b = r, g, b
+		addParticle(p)
+	}
+}
+
+// SparksAt makes sparks where the ball hit something. The sparks are the
+// colour of the ball.
+func sparksAt(x, y float64) {
+	if effectsWanted() == false {
+		return
+	}
+	var ballPicture *themePicture
+	ballPicture = &currentTheme().Ball
+	addParticles(SparkParticles, x, y, 300, 0.4, ballPicture.r, ballPicture.g, ballPicture.b)
+}
+
+// GoalBurstAt makes a burst where the ball went out, in the colour of the bat
+// of the player who won the point.
+func goalBurstAt(x, y float64, winner int) {
+	if effectsWanted() == false {
+		return
+	}
+	var r, g, b uint8
+	if winner == Player {
+		var colour batColour
+		colour = myBatColour()
+		var bat *themePicture
+		bat = &currentTheme().LeftBat
+		r = uint8(int(bat.r) * int(colour.r) / 255)
+		g = uint8(int(bat.g) * int(colour.g) / 255)
+		b = uint8(int(bat.b) * int(colour.b) / 255)
+	} else {
+		r, g, b = currentTheme().RightBat.r, currentTheme().RightBat.g, currentTheme().RightBat.b
+	}
+	addParticles(GoalParticles, x, y, 500, 1, r, g, b)
+}
+
+// Confetti drops confetti in every colour from the top of the playing field.
+func confetti() {
+	if effectsWanted() == false {
+		return
+	}
+	var i int
+	for i = 0; i < ConfettiParticles; i++ {
+		var p particle
+		p.x = rand.Float64() * float64(windowWidth)
+		p.y = -rand.Float64() * float64(windowHeight) / 2
+		p.xSpeed = (rand.Float64() - 0.5) * 100
+		p.ySpeed = rand.Float64() * 100
+		p.size = 4 + rand.Intn(4)
+		p.lifetime = 3 * framesPerSecond
+		p.life = p.lifetime
+		p.r = uint8(rand.Intn(256))
+		p.g = uint8(rand.Intn(256))
+		p.b = uint8(rand.Intn(256))
+		addParticle(p)
+	}
+}
+
+// ClearParticles gets rid of all the particles, ready for a new match.
+func clearParticles() {
+	particles = nil
+	wasShowingGameOver = false
+}
+
+// UpdateParticles moves every particle on for one frame, and throws away the
+// ones that have gone. It also starts the confetti when the match ends, once
+// the instant replay of the last point has finished.
+func updateParticles() {
+	var showingGameOver bool
+	showingGameOver = gameOver == true && showingInstantReplay == false
+	if showingGameOver == true && wasShowingGameOver == false {
+		confetti()
+	}
+	wasShowingGameOver = showingGameOver
+	if paused == true {
+		return
+	}
+	var seconds float64
+	seconds = 1 / float64(framesPerSecond)
+	// the particles that are left are moved to the front of the slice
+	var left int
+	var i int
+	for i = 0; i < len(particles); i++ {
+		var p *particle
+		p = &particles[i]
+		p.life = p.life - 1
+		if p.life <= 0 {
+			continue
+		}
+		p.ySpeed = p.ySpeed + ParticleGravity*seconds
+		p.x = p.x + p.xSpeed*seconds
+		p.y = p.y + p.ySpeed*seconds
+		particles[left] = *p
+		left = left + 1
+	}
+	particles = particles[:left]
+}
+
+// RenderParticles draws the particles. Each one shrinks as it gets older.
+func renderParticles() {
+	var p particle
+	for _, p = range particles {
+		var size int
+		size = p.size * p.life / p.lifetime
+		if size < 1 {
+			size = 1
+		}
+		renderer.SetDrawColor(p.r, p.g, p.b, 255)
+		renderer.FillRect(&sdl.Rect{X: int32(p.x) - int32(size/2), Y: int32(p.y) - int32(size/2), W: int32(size), H: int32(size)})
+	}
+	// put the draw colour back to black. renderer.Clear uses the draw colour.
+	renderer.SetDrawColor(0, 0, 0, 0)
+}
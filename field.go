@@ -0,0 +1,297 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Field modifiers ----
+//
+// Field modifiers change how the ball moves across the playing field.
+// Gravity pulls the ball down towards the bottom of the screen, wind blows
+// the ball left and right in gusts, and zones are parts of the field where the
+// ball slows down or speeds up.
+//
+// An arena is a set of field modifiers with a name. The players pick an arena
+// on the menu, or with the -arena flag on the command line.
+
+// A zone is a rectangle on the playing field where the ball moves at a
+// different speed.
+// The position and size of a zone are fractions of the window size, so a
+// zone with an x of 0.5 starts half way across the window whatever size the
+// window is.
+type zone struct {
+	x float64
+	y float64
+	w float64
+	h float64
+	// speed is how fast the ball moves in the zone. 1 is the normal speed,
+	// 0.5 is half speed and 2 is twice as fast.
+	speed float64
+}
+
+// An arena is a named set of field modifiers
+type arena struct {
+	name string
+	// gravity is how hard the ball is pulled down, in pixels per second per second
+	gravity float64
+	// wind is how hard the strongest gust blows, in pixels per second per second
+	wind float64
+	// the zones on the field
+	zones []zone
+}
+
+// All of the arenas the players can choose from. The first one has no field
+// modifiers at all, it is just the normal game.
+var arenas = [...]arena{
+	{name: "NORMAL"},
+	{name: "MOON", gravity: 150},
+	{name: "JUPITER", gravity: 400},
+	{name: "WINDY", wind: 250},
+	{name: "SWAMP", zones: []zone{
+		{x: 0.25, y: 0.1, w: 0.15, h: 0.35, speed: 0.5},
+		{x: 0.6, y: 0.55, w: 0.15, h: 0.35, speed: 0.5},
+	}},
+	{name: "SPEEDWAY", zones: []zone{
+		{x: 0.3, y: 0.4, w: 0.4, h: 0.2, speed: 1.8},
+	}},
+	{name: "STORM", gravity: 150, wind: 200, zones: []zone{
+		{x: 0.45, y: 0.0, w: 0.1, h: 1.0, speed: 0.6},
+	}},
+}
+
+// The number of the arena the players have chosen
+var arenaNumber int
+
+// The name of the arena chosen on the command line
+var arenaName string
+
+// The field modifiers for this match. These come from the arena, but the
+// gravity and wind can be changed on the command line.
+var gravity float64
+var wind float64
+var zones []zone
+
+// If the players set the gravity or wind on the command line we use their
+// values instead of the values from the arena.
+var gravityFromCommandLine bool
+var windFromCommandLine bool
+var commandLineGravity float64
+var commandLineWind float64
+
+// The number of times the field modifiers have been applied this match. We
+// use this to make the wind change over time.
+var fieldUpdates int
+
+// It takes this many updates for a gust of wind to blow one way, then the
+// other way and then die away again. 4 seconds.
+const GustLength = 4 * UpdatesPerSecond
+
+// FindArena finds the number of the arena with a name, or returns -1 if
+// there is no arena with that name.
+func findArena(name string) int {
+	var i int
+	for i = 0; i < len(arenas); i++ {
+		if arenas[i].name == strings.ToUpper(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ArenaNames returns the names of all of the arenas, for the help message.
+func arenaNames() string {
+	var names []string
+	var i int
+	for i = 0; i < len(arenas); i++ {
+		names = append(names, strings.ToLower(arenas[i].name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// AddFieldFlags adds the command line flags that choose the arena and the
+// field modifiers.
+func addFieldFlags() {
+	flag.StringVar(&arenaName, "arena", "normal", "the arena to play in: "+arenaNames())
+	flag.Float64Var(&commandLineGravity, "gravity", 0, "how hard gravity pulls the ball down, in pixels per second per second")
+	flag.Float64Var(&commandLineWind, "wind", 0, "how hard the strongest gust of wind blows, in pixels per second per second")
+}
+
+// CheckFieldFlags finds the arena chosen on the command line, and remembers
+// if the gravity or the wind were set on the command line, or anywhere else
+// settings come from.
+func checkFieldFlags() error {
+	arenaNumber = findArena(arenaName)
+	if arenaNumber < 0 {
+		return badSetting("arena", "there is no arena called "+arenaName+". Try "+arenaNames())
+	}
+	// flag.Visit calls the function for every flag that was set on the
+	// command line, or by the environment or the settings file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "gravity":
+			gravityFromCommandLine = true
+		case "wind":
+			windFromCommandLine = true
+		}
+	})
+	return nil
+}
+
+// InitialiseFieldModifiers sets up the field modifiers for a new match from
+// the chosen arena.
+func initialiseFieldModifiers() {
+	gravity = arenas[arenaNumber].gravity
+	wind = arenas[arenaNumber].wind
+	zones = arenas[arenaNumber].zones
+	if gravityFromCommandLine == true {
+		gravity = commandLineGravity
+	}
+	if windFromCommandLine == true {
+		wind = commandLineWind
+	}
+	fieldUpdates = 0
+}
+
+// FieldModifiersActive returns true if anything is changing how the ball moves.
+func fieldModifiersActive() bool {
+	return gravity != 0 || wind != 0 || len(zones) > 0
+}
+
+// WindNow works out how hard the wind is blowing after a number of updates.
+// Two waves that go up and down at different speeds are added together, so
+// the gusts don't feel too regular. A positive number blows the ball to the
+// right and a negative number blows it to the left.
+func windNow(update int) float64 {
+	if wind == 0 {
+		return 0
+	}
+	var t float64
+	t = 2 * math.Pi * float64(update) / GustLength
+	return wind * (0.7*math.Sin(t) + 0.3*math.Sin(2.3*t))
+}
+
+// ApplyFieldForces changes the balls direction by the gravity and the wind
+// for one update. We pass the direction in and get the new direction back,
+// rather than changing ballDirX and ballDirY, so the computers AI can use
+// the same rules to work out where the ball is going.
+func applyFieldForces(dirX, dirY float64, update int) (float64, float64) {
+	var frameTime = float64(1) / float64(UpdatesPerSecond)
+	dirY = dirY + gravity*frameTime
+	dirX = dirX + windNow(update)*frameTime
+	return dirX, dirY
+}
+
+// ZoneSpeed works out how fast a ball at x and y should move. If the middle
+// of the ball is in a zone the ball moves at the speed of that zone, if not it
+// moves at the normal speed.
+func zoneSpeed(x, y float64) float64 {
+	var middleX = (x + float64(ballW)/2) / float64(windowWidth)
+	var middleY = (y + float64(ballH)/2) / float64(windowHeight)
+	var i int
+	for i = 0; i < len(zones); i++ {
+		if middleX >= zones[i].x && middleX < zones[i].x+zones[i].w &&
+			middleY >= zones[i].y && middleY < zones[i].y+zones[i].h {
+			return zones[i].speed
+		}
+	}
+	return 1
+}
+
+// PredictBallY works out where the middle of the ball will be, up and down
+// the screen, when it reaches the computers bat. It moves a pretend ball
+// using the same rules as the real ball, including the field modifiers and
+// bouncing off the top and bottom walls.
+// If the ball is not going to reach the computers bat we just return where
+// the middle of the ball is now.
+func predictBallY() float64 {
+	var x, y = ballX, ballY
+	var dirX, dirY = ballDirX, ballDirY
+	var frameTime = float64(1) / float64(UpdatesPerSecond)
+	var playingFieldBottom = float64(windowHeight) - float64(ballH)
+	var update int
+	// look at most 5 seconds ahead, that is far enough for the ball to cross
+	// the whole field
+	for update = 0; update < 5*UpdatesPerSecond; update++ {
+		if dirX <= 0 {
+			break
+		}
+		if x+float64(ballW) >= float64(computersBatX) {
+			return y + float64(ballH)/2
+		}
+		dirX, dirY = applyFieldForces(dirX, dirY, fieldUpdates+update)
+		var speed = zoneSpeed(x, y)
+		x = x + dirX*frameTime*speed
+		y = y + dirY*frameTime*speed
+		if y < 0 {
+			y = 0
+			dirY = dirY * -1
+		} else if y > playingFieldBottom {
+			y = playingFieldBottom
+			dirY = dirY * -1
+		}
+	}
+	return ballY + float64(ballH)/2
+}
+
+// RenderFieldModifiers draws the zones and shows which way the wind is
+// blowing and whether there is any gravity. They are drawn first, so the
+// bats and the ball are drawn on top.
+func renderFieldModifiers() {
+	renderZones()
+	var y int
+	y = windowHeight - 24
+	if gravity != 0 {
+		renderText(fmt.Sprintf("GRAVITY %d", int(gravity)), 16, y, 2, 128, 128, 255)
+	}
+	if wind != 0 {
+		renderWind(y)
+	}
+}
+
+// RenderZones draws each zone as a see through rectangle. Zones that slow the
+// ball down are blue and zones that speed it up are red.
+func renderZones() {
+	// blending lets us draw colours that you can see through
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	var i int
+	for i = 0; i < len(zones); i++ {
+		if zones[i].speed < 1 {
+			renderer.SetDrawColor(0, 64, 255, 64)
+		} else {
+			renderer.SetDrawColor(255, 32, 0, 64)
+		}
+		var dst sdl.Rect
+		dst.X = int32(zones[i].x * float64(windowWidth))
+		dst.Y = int32(zones[i].y * float64(windowHeight))
+		dst.W = int32(zones[i].w * float64(windowWidth))
+		dst.H = int32(zones[i].h * float64(windowHeight))
+		renderer.FillRect(&dst)
+	}
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	renderer.SetDrawColor(0, 0, 0, 0)
+}
+
+// RenderWind draws a bar at the bottom of the screen that shows which way the
+// wind is blowing and how hard. The longer the bar the stronger the wind.
+func renderWind(y int) {
+	renderTextCentred("WIND", windowWidth/2, y-20, 2, 128, 255, 128)
+	var strength float64
+	strength = windNow(fieldUpdates) / math.Abs(wind)
+	var bar sdl.Rect
+	bar.W = int32(math.Abs(strength) * float64(windowWidth) / 8)
+	bar.H = 8
+	bar.Y = int32(y)
+	if strength < 0 {
+		bar.X = int32(windowWidth/2) - bar.W
+	} else {
+		bar.X = int32(windowWidth / 2)
+	}
+	renderer.SetDrawColor(128, 255, 128, 255)
+	renderer.FillRect(&bar)
+	renderer.SetDrawColor(0, 0, 0, 0)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// ---- Handicaps ----
+//
+// Handicaps let a strong player give a weaker player a fair game. Each side
+// can have its own bat height, bat speed, starting score and target score.
+// The left side is my side and the right side is the computers side.
+
+// The height of each players bat in pixels. If the height is zero the bat is
+// the same height as the bat graphic.
+var myBatHeight int
+var computersBatHeight int
+
+// The speed of each players bat, as a percentage of the normal speed. 100
+// is the normal speed, 50 is half speed and 200 is twice as fast.
+var myBatSpeed int
+var computersBatSpeed int
+
+// The score each player starts the match with.
+var myStartingScore int
+var computersStartingScore int
+
+// The score each player needs to win the match.
+var myTargetScore int
+var computersTargetScore int
+
+// The smallest bat we allow, in pixels. Any smaller and the bat would be
+// impossible to see.
+const MinimumBatHeight = 10
+
+// AddHandicapFlags adds the command line flags that set the handicaps.
+func addHandicapFlags() {
+	flag.IntVar(&myBatHeight, "left-bat-height", 0, "the height of the left bat in pixels. Zero means the height of the bat graphic")
+	flag.IntVar(&computersBatHeight, "right-bat-height", 0, "the height of the right bat in pixels. Zero means the height of the bat graphic")
+	flag.IntVar(&myBatSpeed, "left-bat-speed", 100, "the speed of the left bat as a percentage of the normal speed")
+	flag.IntVar(&computersBatSpeed, "right-bat-speed", 100, "the speed of the right bat as a percentage of the normal speed")
+	flag.IntVar(&myStartingScore, "left-start-score", 0, "the score the left player starts with")
+	flag.IntVar(&computersStartingScore, "right-start-score", 0, "the score the right player starts with")
+	flag.IntVar(&myTargetScore, "left-target-score", 0, "the score the left player needs to win. Zero means the winning score")
+	flag.IntVar(&computersTargetScore, "right-target-score", 0, "the score the right player needs to win. Zero means the winning score")
+}
+
+// CheckHandicaps makes sure the handicaps make sense. A target score of zero
+// is changed to the winning score.
+func checkHandicaps() error {
+	if myTargetScore == 0 {
+		myTargetScore = winningScore
+	}
+	if computersTargetScore == 0 {
+		computersTargetScore = winningScore
+	}
+	var err error
+	err = checkHandicap("left", myBatHeight, myBatSpeed, myStartingScore, myTargetScore)
+	if err != nil {
+		return err
+	}
+	return checkHandicap("right", computersBatHeight, computersBatSpeed, computersStartingScore, computersTargetScore)
+}
+
+// CheckHandicap checks the handicaps for one side.
+func checkHandicap(side string, batHeight, batSpeed, startingScore, targetScore int) error {
+	if batHeight != 0 && batHeight < MinimumBatHeight {
+		return badSetting(side+"-bat-height", fmt.Sprintf("the %s bat height must be at least %d pixels", side, MinimumBatHeight))
+	} else if batHeight > FieldHeight {
+		// the window might not be open yet, so the bat is checked against
+		// the playing field instead
+		return badSetting(side+"-bat-height", fmt.Sprintf("the %s bat height can't be more than %d pixels", side, FieldHeight))
+	} else if batSpeed <= 0 {
+		return badSetting(side+"-bat-speed", fmt.Sprintf("the %s bat speed must be more than zero", side))
+	} else if targetScore < 1 || targetScore > HighestScore {
+		// we only have graphics for the scores 0 to 11
+		return badSetting(side+"-target-score", fmt.Sprintf("the %s target score must be between 1 and %d", side, HighestScore))
+	} else if startingScore < 0 || startingScore >= targetScore {
+		return badSetting(side+"-start-score", fmt.Sprintf("the %s starting score must be between 0 and %d", side, targetScore-1))
+	}
+	return nil
+}
+
+// MyBatStep is how far my bat moves each time a cursor key is pressed.
+// Normally it moves a quarter of the height of the bat, so a taller bat moves
+// further, just as it does when it is the height of the bat graphic.
+func myBatStep() int {
+	return myBatH / 4 * myBatSpeed / 100
+}
+
+// ComputersBatStep is how far the computers bat moves each time a cursor key is
+// pressed, when a person is moving it instead of the AI.
+func computersBatStep() int {
+	return computersBatH / 4 * computersBatSpeed / 100
+}
+
+// ComputersBatPixelsPerSecond is how far the computers bat can move in one second.
+func computersBatPixelsPerSecond() float64 {
+	return float64(computerSpeed) * float64(computersBatSpeed) / 100
+}
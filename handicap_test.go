@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+// TestBatStep checks a bat moves a quarter of its own height each time a key
+// is pressed, however tall the handicap makes it.
+func TestBatStep(t *testing.T) {
+	setUpTestMatch(t)
+	myBatHeight = 200
+	computersBatHeight = 40
+	computersBatSpeed = 50
+	setPlainSizes()
+	if myBatStep() != 50 {
+		t.Errorf("the left bat moves %d pixels", myBatStep())
+	}
+	if computersBatStep() != 5 {
+		t.Errorf("the right bat moves %d pixels", computersBatStep())
+	}
+}
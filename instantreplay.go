@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Instant replay ----
+//
+// After a point is scored the game can show the last few seconds of the
+// rally again in slow motion, like on the television, before the ball is
+// served again.
+//
+// To do that the game remembers where the bats and the ball were on every
+// frame of the rally. It only needs the last few seconds, so it keeps them in
+// a "ring buffer". That is an array used as if its ends were joined together
+// in a ring: when the array is full the next frame goes back in at the start,
+// over the top of the oldest frame. So the array always holds the most recent
+// frames, and never needs to grow.
+//
+// The replay only shows where things were. It doesn't change the game at
+// all, so the game carries on exactly where it stopped when the replay ends.
+
+// How many seconds of the rally the instant replay shows.
+const InstantReplaySeconds = 3
+
+// How many frames the ring buffer holds.
+const InstantReplayFrames = InstantReplaySeconds * UpdatesPerSecond
+
+// How fast the instant replay plays. A quarter of the normal speed is slow
+// enough to see exactly how the point was won.
+const InstantReplaySpeed = 0.25
+
+// A rallyFrame is where the bats and the ball were on one frame.
+type rallyFrame struct {
+	myBatY        int
+	computersBatY int
+	ballX         float64
+	ballY         float64
+}
+
+// The instantReplay flag is true if the player asked for instant replays.
+var instantReplay bool
+
+// The ring buffer of the frames of the rally. nextRallyFrame is where the next
+// frame goes, and rallyFrameCount is how many frames are in the buffer.
+var rallyFrames [InstantReplayFrames]rallyFrame
+var nextRallyFrame int
+var rallyFrameCount int
+
+// The showingInstantReplay flag is true while the instant replay is on the
+// screen.
+var showingInstantReplay bool
+
+// The frames being replayed, oldest first, and how far through them the
+// replay has got.
+var instantReplayFrames []rallyFrame
+var instantReplayPosition float64
+
+// AddInstantReplayFlags adds the command line flag that turns instant
+// replays on.
+func addInstantReplayFlags() {
+	flag.BoolVar(&instantReplay, "instant-replay", false, "show the end of each rally again in slow motion after a point is scored")
+}
+
+// RecordRallyFrame adds where the bats and the ball are now to the ring
+// buffer. It is called once every frame while the ball is moving.
+func recordRallyFrame() {
+	var frame rallyFrame
+	frame.myBatY = myBatY
+	frame.computersBatY = computersBatY
+	frame.ballX = ballX
+	frame.ballY = ballY
+	rallyFrames[nextRallyFrame] = frame
+	// go round the ring
+	nextRallyFrame = (nextRallyFrame + 1) % InstantReplayFrames
+	if rallyFrameCount < InstantReplayFrames {
+		rallyFrameCount = rallyFrameCount + 1
+	}
+}
+
+// ClearRallyFrames empties the ring buffer, ready for the next rally.
+func clearRallyFrames() {
+	nextRallyFrame = 0
+	rallyFrameCount = 0
+}
+
+// StartInstantReplay starts showing the rally that has just finished. It is
+// called when a point is scored. Instant replays are only shown in games
+// played on this computer: in a network game the other player would have to
+// wait, and a replay file plays back without them.
+func startInstantReplay() {
+	if instantReplay == false || networkRole != NotNetworked || playingBack == true {
+		clearRallyFrames()
+		return
+	}
+	// copy the frames out of the ring, starting with the oldest one
+	instantReplayFrames = nil
+	var oldest int
+	oldest = (nextRallyFrame - rallyFrameCount + InstantReplayFrames) % InstantReplayFrames
+	var i int
+	for i = 0; i < rallyFrameCount; i++ {
+		instantReplayFrames = append(instantReplayFrames, rallyFrames[(oldest+i)%InstantReplayFrames])
+	}
+	clearRallyFrames()
+	if len(instantReplayFrames) == 0 {
+		return
+	}
+	instantReplayPosition = 0
+	showingInstantReplay = true
+}
+
+// UpdateInstantReplay moves the instant replay on. It is called every frame
+// instead of updateState while the replay is on the screen.
+func updateInstantReplay() {
+	instantReplayPosition = instantReplayPosition + InstantReplaySpeed
+	if int(instantReplayPosition) >= len(instantReplayFrames) {
+		stopInstantReplay()
+	}
+}
+
+// StopInstantReplay goes back to the game.
+func stopInstantReplay() {
+	showingInstantReplay = false
+	instantReplayFrames = nil
+}
+
+// HandleInstantReplayEvent lets the players skip the instant replay by
+// pressing any key.
+func handleInstantReplayEvent(event sdl.Event) {
+	if isKeyDownEvent(event) == true {
+		stopInstantReplay()
+	}
+}
+
+// RenderInstantReplay draws the bats and the ball where they were in the
+// frame being replayed, with a banner so nobody thinks the game is running.
+func renderInstantReplay() {
+	var frame rallyFrame
+	frame = instantReplayFrames[int(instantReplayPosition)]
+	// swap the replayed positions in, draw them, then swap the real ones back
+	var savedMyBatY, savedComputersBatY int
+	var savedBallX, savedBallY float64
+	savedMyBatY = myBatY
+	savedComputersBatY = computersBatY
+	savedBallX = ballX
+	savedBallY = ballY
+	myBatY = frame.myBatY
+	computersBatY = frame.computersBatY
+	ballX = frame.ballX
+	ballY = frame.ballY
+	renderMyBat()
+	renderComputersBat()
+	renderBall()
+	myBatY = savedMyBatY
+	computersBatY = savedComputersBatY
+	ballX = savedBallX
+	ballY = savedBallY
+	renderTextCentred("REPLAY", windowWidth/2, windowHeight-90, 6, 255, 64, 64)
+	renderTextCentred("PRESS ANY KEY TO SKIP", windowWidth/2, windowHeight-28, 2, 128, 128, 128)
+}
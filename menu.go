@@ -0,0 +1,246 @@
+package main
+
+import (
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- The mode menu ----
+//
+// Before the game starts we show a menu so the players can choose how they
+// want to play. The up and down cursor keys move between the menu items, the
+// left and right cursor keys change the selected item, and the return key
+// starts the game.
+//
+// The profile screen and the records screen are part of the menu. While one
+// of them is showing it gets the keys instead of the menu.
+
+// The inMenu flag is true while the menu is on the screen. While the menu is
+// on the screen the game does not update.
+var inMenu bool
+
+// The items on the menu. The number of each item is its position on the menu,
+// counting from the top.
+const MenuContinue = 0
+const MenuMode = 1
+const MenuTimeLimit = 2
+const MenuArena = 3
+const MenuDifficulty = 4
+const MenuTheme = 5
+const MenuStart = 6
+const MenuPlayer = 7
+const MenuRecords = 8
+const MenuNetwork = 9
+const MenuNetcode = 10
+const MenuTournament = 11
+
+// The number of items on the menu
+const NumberOfMenuItems = 12
+
+// The gap between the lines of the menu, in pixels.
+const MenuLineSpacing = 40
+
+// The menu item the player has selected.
+var menuSelection int
+
+// The time limits the player can choose from on the menu.
+// A time limit of zero means the match is not timed.
+var timeLimitChoices = [...]time.Duration{
+	0,
+	1 * time.Minute,
+	2 * time.Minute,
+	3 * time.Minute,
+	5 * time.Minute,
+}
+
+// HandleMenuEvent responds to the keys the player presses while the menu is on
+// the screen.
+func handleMenuEvent(event sdl.Event) {
+	if inProfileScreen == true {
+		handleProfileEvent(event)
+		return
+	}
+	if inRecordsScreen == true {
+		handleRecordsEvent(event)
+		return
+	}
+	if isKeyDownEvent(event) == false {
+		return
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_UP:
+		menuSelection = menuSelection - 1
+		if menuSelection < 0 {
+			menuSelection = NumberOfMenuItems - 1
+		}
+	case sdl.K_DOWN:
+		menuSelection = menuSelection + 1
+		if menuSelection >= NumberOfMenuItems {
+			menuSelection = 0
+		}
+	case sdl.K_LEFT:
+		changeMenuItem(-1)
+	case sdl.K_RIGHT:
+		changeMenuItem(1)
+	case sdl.K_RETURN:
+		if menuSelection == MenuNetwork {
+			openNetworkScreen()
+			return
+		}
+		if menuSelection == MenuTournament {
+			openTournamentScreen()
+			return
+		}
+		if menuSelection == MenuPlayer {
+			openProfileScreen()
+			return
+		}
+		if menuSelection == MenuRecords {
+			openRecordsScreen()
+			return
+		}
+		if menuSelection == MenuContinue {
+			if haveSavedMatch == true {
+				continueSavedMatch()
+			}
+			return
+		}
+		inMenu = false
+		startNewGame()
+	case sdl.K_ESCAPE:
+		quit = true
+	}
+}
+
+// ChangeMenuItem changes the setting of the selected menu item. Step is -1 to
+// go back to the previous setting and 1 to go on to the next one.
+func changeMenuItem(step int) {
+	switch menuSelection {
+	case MenuMode:
+		if gameMode == ClassicMode {
+			gameMode = BreakoutMode
+		} else {
+			gameMode = ClassicMode
+		}
+	case MenuTimeLimit:
+		timeLimit = nextTimeLimit(timeLimit, step)
+	case MenuArena:
+		arenaNumber = arenaNumber + step
+		if arenaNumber < 0 {
+			arenaNumber = len(arenas) - 1
+		} else if arenaNumber >= len(arenas) {
+			arenaNumber = 0
+		}
+	case MenuDifficulty:
+		nextDifficulty(step)
+	case MenuTheme:
+		nextTheme(step)
+	case MenuPlayer:
+		changeProfile(step)
+		saveProfiles()
+	case MenuNetcode:
+		if netcode == NetcodeHost {
+			netcode = NetcodeRollback
+		} else {
+			netcode = NetcodeHost
+		}
+	}
+}
+
+// NextTimeLimit finds the time limit that comes after (or before, if step is
+// -1) the current one. The current time limit might not be one of the
+// choices if it was set on the command line, so we look for the nearest
+// choice instead.
+func nextTimeLimit(current time.Duration, step int) time.Duration {
+	var i int
+	if step > 0 {
+		for i = 0; i < len(timeLimitChoices); i++ {
+			if timeLimitChoices[i] > current {
+				return timeLimitChoices[i]
+			}
+		}
+		// go round to the start again
+		return timeLimitChoices[0]
+	}
+	for i = len(timeLimitChoices) - 1; i >= 0; i-- {
+		if timeLimitChoices[i] < current {
+			return timeLimitChoices[i]
+		}
+	}
+	// go round to the end again
+	return timeLimitChoices[len(timeLimitChoices)-1]
+}
+
+// RenderMenu draws the menu.
+func renderMenu() {
+	if inProfileScreen == true {
+		renderProfileScreen()
+		return
+	}
+	if inRecordsScreen == true {
+		renderRecordsScreen()
+		return
+	}
+	renderTextCentred("PONG", windowWidth/2, windowHeight/8, 16, 255, 255, 255)
+
+	var mode string
+	if gameMode == BreakoutMode {
+		mode = "BREAKOUT"
+	} else {
+		mode = "CLASSIC"
+	}
+	var limit string
+	if timeLimit == 0 {
+		limit = "OFF"
+	} else {
+		limit = formatClock(timeLimit)
+	}
+
+	// continue is grey when there isn't a saved match
+	if haveSavedMatch == true {
+		renderMenuItem(MenuContinue, "CONTINUE")
+	} else {
+		renderTextCentred("CONTINUE", windowWidth/2, menuItemY(MenuContinue), 4, 96, 96, 96)
+	}
+	renderMenuItem(MenuMode, "MODE: "+mode)
+	renderMenuItem(MenuTimeLimit, "TIME LIMIT: "+limit)
+	renderMenuItem(MenuArena, "ARENA: "+arenas[arenaNumber].name)
+	renderMenuItem(MenuDifficulty, "COMPUTER: "+difficultyNames[difficulty])
+	renderMenuItem(MenuTheme, "THEME: "+currentTheme().Name)
+	renderMenuItem(MenuStart, "START")
+	renderMenuItem(MenuPlayer, "PLAYER: "+playersProfile().Name)
+	renderMenuItem(MenuRecords, "RECORDS")
+	renderMenuItem(MenuNetwork, "PLAY OVER THE NETWORK")
+	var netcodeChoice string
+	if netcode == NetcodeRollback {
+		netcodeChoice = "ROLLBACK"
+	} else {
+		netcodeChoice = "HOST"
+	}
+	renderMenuItem(MenuNetcode, "NETCODE: "+netcodeChoice)
+	renderMenuItem(MenuTournament, "TOURNAMENT")
+
+	renderTextCentred("UP/DOWN TO CHOOSE, LEFT/RIGHT TO CHANGE, RETURN TO PLAY",
+		windowWidth/2, windowHeight-48, 2, 128, 128, 128)
+}
+
+// RenderMenuItem draws one line of the menu. The selected item is drawn in
+// yellow with an arrow next to it. Each item is drawn MenuLineSpacing pixels
+// below the one before.
+func renderMenuItem(item int, text string) {
+	var y int
+	y = menuItemY(item)
+	if item == menuSelection {
+		renderTextCentred("> "+text+" <", windowWidth/2, y, 4, 255, 255, 0)
+	} else {
+		renderTextCentred(text, windowWidth/2, y, 4, 255, 255, 255)
+	}
+}
+
+// MenuItemY works out how far down the screen a menu item is drawn.
+func menuItemY(item int) int {
+	return windowHeight/2 - 160 + item*MenuLineSpacing
+}
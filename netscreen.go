@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Finding games on the network ----
+//
+// A computer that is hosting a game tells every other computer on the local
+// network about it, once every second. It does this by broadcasting a short
+// UDP message. A UDP message is not guaranteed to arrive, but that doesn't
+// matter because another one will be along in a second.
+//
+// The network screen listens for these messages and shows a list of the
+// games the player can join.
+
+// The UDP port the announcements are sent to.
+const DiscoveryPort = 7778
+
+// Every announcement starts with this, so we can ignore any other messages
+// that happen to arrive on the DiscoveryPort.
+const AnnouncementPrefix = "PONG"
+
+// If we have not heard about a game for this long, it has probably finished.
+const GameForgottenAfter = 5 * time.Second
+
+// A game that we have heard about on the network
+type discoveredGame struct {
+	name     string
+	address  string
+	lastSeen time.Time
+}
+
+// The inNetworkScreen flag is true while the host/join screen is on the screen.
+var inNetworkScreen bool
+
+// The games we have heard about. The list is kept in the order we heard
+// about them, so the games don't jump around on the screen.
+var discoveredGames []discoveredGame
+
+// The line on the network screen the player has selected. Line zero is
+// "HOST A GAME" and after that each discovered game has two lines, one to
+// join it and one to watch it.
+var networkScreenSelection int
+
+// A message to show at the bottom of the network screen, for example if we
+// could not join a game.
+var networkScreenMessage string
+
+// The announcements we hear arrive on this channel.
+var announcements chan discoveredGame
+
+// The connection we listen for announcements on.
+var discoveryConn *net.UDPConn
+
+// Closing this channel stops the host announcing its game.
+var stopAnnouncements chan bool
+
+// ---- Announcing ----
+
+// StartAnnouncing starts telling the other computers on the network about
+// the game we are hosting.
+func startAnnouncing() {
+	var name string
+	var err error
+	name, err = os.Hostname()
+	if err != nil || name == "" {
+		name = "PONG"
+	}
+	// the name can't have a space in it, because we use spaces to separate
+	// the parts of the announcement
+	name = strings.Replace(name, " ", "-", -1)
+	var message string
+	message = fmt.Sprintf("%s %d %s %d", AnnouncementPrefix, NetworkProtocolVersion, name, GamePort)
+	stopAnnouncements = make(chan bool)
+	go announce(message, stopAnnouncements)
+}
+
+// StopAnnouncing stops telling the other computers about our game.
+func stopAnnouncing() {
+	if stopAnnouncements != nil {
+		close(stopAnnouncements)
+		stopAnnouncements = nil
+	}
+}
+
+// Announce broadcasts the announcement every second until the stop channel
+// is closed.
+// We send the announcement to the broadcast address, which reaches every
+// computer on the local network, and to the loopback address, which reaches
+// this computer, so two copies of the game on one computer can find each other.
+func announce(message string, stop chan bool) {
+	var conn net.PacketConn
+	var err error
+	conn, err = net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var destinations = []*net.UDPAddr{
+		{IP: net.IPv4bcast, Port: DiscoveryPort},
+		{IP: net.IPv4(127, 0, 0, 1), Port: DiscoveryPort},
+	}
+	var ticker *time.Ticker
+	ticker = time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		var destination *net.UDPAddr
+		for _, destination = range destinations {
+			conn.WriteTo([]byte(message), destination)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ---- Listening ----
+
+// StartListeningForGames starts listening for announcements.
+func startListeningForGames() error {
+	var err error
+	discoveryConn, err = net.ListenUDP("udp4", &net.UDPAddr{Port: DiscoveryPort})
+	if err != nil {
+		return err
+	}
+	announcements = make(chan discoveredGame, 16)
+	go listenForAnnouncements(discoveryConn, announcements)
+	return nil
+}
+
+// StopListeningForGames stops listening for announcements.
+func stopListeningForGames() {
+	if discoveryConn != nil {
+		discoveryConn.Close()
+		discoveryConn = nil
+	}
+}
+
+// ListenForAnnouncements reads announcements until the connection is closed.
+func listenForAnnouncements(conn *net.UDPConn, found chan discoveredGame) {
+	var buffer [256]byte
+	for {
+		var n int
+		var from *net.UDPAddr
+		var err error
+		n, from, err = conn.ReadFromUDP(buffer[:])
+		if err != nil {
+			// the connection has been closed
+			return
+		}
+		var game discoveredGame
+		var ok bool
+		game, ok = parseAnnouncement(string(buffer[:n]), from)
+		if ok {
+			select {
+			case found <- game:
+			default:
+			}
+		}
+	}
+}
+
+// ParseAnnouncement reads an announcement. An announcement looks like this
+//
+//	PONG 3 classroom-pc 7777
+//
+// which is the prefix, the version of the network messages, the name of the
+// host and the port to join the game on. We ignore announcements from
+// different versions of the game, because we could not play them anyway.
+func parseAnnouncement(message string, from *net.UDPAddr) (discoveredGame, bool) {
+	var game discoveredGame
+	var parts []string
+	parts = strings.Fields(message)
+	if len(parts) != 4 || parts[0] != AnnouncementPrefix {
+		return game, false
+	}
+	if parts[1] != strconv.Itoa(NetworkProtocolVersion) {
+		return game, false
+	}
+	var port int
+	var err error
+	port, err = strconv.Atoi(parts[3])
+	if err != nil {
+		return game, false
+	}
+	game.name = parts[2]
+	game.address = net.JoinHostPort(from.IP.String(), strconv.Itoa(port))
+	game.lastSeen = time.Now()
+	return game, true
+}
+
+// PollDiscoveredGames adds the games we have just heard about to the list,
+// and removes the games we have not heard about for a while.
+func pollDiscoveredGames() {
+	for {
+		select {
+		case game := <-announcements:
+			rememberGame(game)
+		default:
+			forgetOldGames()
+			return
+		}
+	}
+}
+
+// RememberGame adds a game to the list, or updates it if it is already there.
+func rememberGame(game discoveredGame) {
+	var i int
+	for i = 0; i < len(discoveredGames); i++ {
+		if discoveredGames[i].address == game.address {
+			discoveredGames[i] = game
+			return
+		}
+	}
+	discoveredGames = append(discoveredGames, game)
+}
+
+// ForgetOldGames removes the games we have not heard about for a while.
+func forgetOldGames() {
+	var kept []discoveredGame
+	var game discoveredGame
+	for _, game = range discoveredGames {
+		if time.Since(game.lastSeen) < GameForgottenAfter {
+			kept = append(kept, game)
+		}
+	}
+	discoveredGames = kept
+	// make sure the selection is still on the screen
+	if networkScreenSelection > lastNetworkScreenLine() {
+		networkScreenSelection = lastNetworkScreenLine()
+	}
+}
+
+// LastNetworkScreenLine is the number of the bottom line of the network screen.
+func lastNetworkScreenLine() int {
+	return 2 * len(discoveredGames)
+}
+
+// ---- The network screen ----
+
+// OpenNetworkScreen shows the host/join screen.
+func openNetworkScreen() {
+	inMenu = false
+	inNetworkScreen = true
+	networkScreenSelection = 0
+	networkScreenMessage = ""
+	discoveredGames = nil
+	var err error
+	err = startListeningForGames()
+	if err != nil {
+		networkScreenMessage = "CANNOT LOOK FOR GAMES: " + err.Error()
+	}
+}
+
+// CloseNetworkScreen hides the host/join screen.
+func closeNetworkScreen() {
+	stopListeningForGames()
+	inNetworkScreen = false
+}
+
+// HandleNetworkScreenEvent responds to the keys pressed on the network screen.
+func handleNetworkScreenEvent(event sdl.Event) {
+	if isKeyDownEvent(event) == false {
+		return
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_UP:
+		if networkScreenSelection > 0 {
+			networkScreenSelection = networkScreenSelection - 1
+		}
+	case sdl.K_DOWN:
+		if networkScreenSelection < lastNetworkScreenLine() {
+			networkScreenSelection = networkScreenSelection + 1
+		}
+	case sdl.K_ESCAPE:
+		closeNetworkScreen()
+		inMenu = true
+	case sdl.K_RETURN:
+		chooseNetworkScreenLine()
+	}
+}
+
+// ChooseNetworkScreenLine hosts a game, or joins or watches the selected game.
+func chooseNetworkScreenLine() {
+	var err error
+	if networkScreenSelection == 0 {
+		// we have to stop listening before we host, otherwise we would
+		// hear our own announcements
+		stopListeningForGames()
+		err = hostGame()
+	} else {
+		var game discoveredGame
+		game = discoveredGames[(networkScreenSelection-1)/2]
+		if networkScreenSelection%2 == 1 {
+			err = joinGame(game.address)
+		} else {
+			err = watchGame(game.address)
+		}
+	}
+	if err != nil {
+		networkScreenMessage = "FAILED: " + err.Error()
+		// start listening again so the list of games stays up to date
+		if discoveryConn == nil {
+			startListeningForGames()
+		}
+		return
+	}
+	closeNetworkScreen()
+}
+
+// RenderNetworkScreen draws the host/join screen.
+func renderNetworkScreen() {
+	renderTextCentred("NETWORK GAME", windowWidth/2, windowHeight/8, 8, 255, 255, 255)
+
+	var y int
+	y = windowHeight / 3
+	renderNetworkScreenLine(0, "HOST A GAME", y)
+	if len(discoveredGames) == 0 {
+		renderTextCentred("LOOKING FOR GAMES TO JOIN...", windowWidth/2, y+80, 2, 128, 128, 128)
+	}
+	var i int
+	for i = 0; i < len(discoveredGames); i++ {
+		var host string
+		host, _, _ = net.SplitHostPort(discoveredGames[i].address)
+		renderNetworkScreenLine(2*i+1, "JOIN "+discoveredGames[i].name+" ("+host+")", y+80+i*80)
+		renderNetworkScreenLine(2*i+2, "WATCH "+discoveredGames[i].name, y+120+i*80)
+	}
+	if networkScreenMessage != "" {
+		renderTextCentred(networkScreenMessage, windowWidth/2, windowHeight-96, 2, 255, 64, 64)
+	}
+	renderTextCentred("UP/DOWN TO CHOOSE, RETURN TO HOST, JOIN OR WATCH, ESCAPE FOR THE MENU",
+		windowWidth/2, windowHeight-48, 2, 128, 128, 128)
+}
+
+// RenderNetworkScreenLine draws one line of the network screen. The selected
+// line is drawn in yellow with arrows next to it.
+func renderNetworkScreenLine(line int, text string, y int) {
+	if line == networkScreenSelection {
+		renderTextCentred("> "+text+" <", windowWidth/2, y, 3, 255, 255, 0)
+	} else {
+		renderTextCentred(text, windowWidth/2, y, 3, 255, 255, 255)
+	}
+}
@@ -0,0 +1,906 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Network games ----
+//
+// Two players on two computers can play each other over the network.
+// One computer hosts the game. It runs the game exactly as normal, except the
+// computers bat on the right is moved by the other player instead of the AI.
+// The other computer joins the game. It does not run the game at all. It
+// sends the keys its player presses to the host, and the host sends back
+// where everything is, every frame, so it can be drawn.
+//
+// The computers talk to each other over a TCP connection. TCP makes sure
+// everything we send arrives, and arrives in the same order we sent it.
+
+// The network roles. A computer is either not playing over the network, or it
+// is hosting the game, or it has joined a game that another computer is
+// hosting, or it is watching a game that another computer is hosting.
+const NotNetworked = 0
+const Hosting = 1
+const Joined = 2
+const Spectating = 3
+
+// The network role of this computer
+var networkRole int
+
+// The TCP port the host listens on for players joining the game.
+const GamePort = 7777
+
+// The version of the messages we send over the network. If we change the
+// messages we must change the version, so that two different versions of the
+// game don't try to play each other.
+const NetworkProtocolVersion = 7
+
+// If we don't hear anything from the other computer for this long we decide
+// the connection has been lost.
+const NetworkTimeout = 3 * time.Second
+
+// The kinds of message the computers send to each other
+const (
+	// Hello is the first message a joining computer sends. It tells the host
+	// which version of the messages the joining computer understands, and
+	// whether it wants to play or watch.
+	MsgHello = iota
+	// Rejected tells the joining computer it cannot join the game, and why.
+	MsgRejected
+	// Settings tells the joining computer how the match is set up.
+	MsgSettings
+	// State tells the joining computer where everything is.
+	MsgState
+	// Input tells the host which key the joining player pressed.
+	MsgInput
+	// Ping lets the other computer know we are still there when there is
+	// nothing else to send.
+	MsgPing
+	// ListLobbies asks a dedicated server which lobbies it has.
+	MsgListLobbies
+	// Lobbies is the server's answer to ListLobbies.
+	MsgLobbies
+)
+
+// The keys the joining player can press
+const InputUp = 1
+const InputDown = 2
+const InputPause = 3
+
+// A netMessage is one message sent over the network. Only the parts of the
+// message that go with its Kind are filled in.
+// The names start with capital letters because the gob package can only send
+// the parts of a struct that are exported.
+type netMessage struct {
+	Kind        int
+	Version     int
+	Reason      string
+	Input       int
+	UDPPort     int
+	Spectator   bool
+	Spectators  int
+	Lobby       string
+	CreateLobby bool
+	Lobbies     []lobbyInfo
+	Settings    *netSettings
+	State       *netState
+}
+
+// NetSettings are the parts of the game that don't change during a match.
+type netSettings struct {
+	GameMode             int
+	ArenaNumber          int
+	Gravity              float64
+	Wind                 float64
+	TimeLimit            time.Duration
+	MyBatH               int
+	ComputersBatH        int
+	MyBatSpeed           int
+	ComputersBatSpeed    int
+	MyTargetScore        int
+	ComputersTargetScore int
+	BrickLayout          []string
+	Difficulty           int
+	BallSpeed            int
+	ComputerSpeed        int
+	// These are only used with rollback. Both computers start from the
+	// same game state, and send their inputs to the UDPPort.
+	Netcode    int
+	UDPPort    int
+	StartState *gameState
+}
+
+// NetState is everything the joining computer needs to draw a frame.
+type netState struct {
+	MyBatY           int
+	ComputersBatY    int
+	BallX            float64
+	BallY            float64
+	MyScore          int
+	ComputersScore   int
+	Paused           bool
+	GameOver         bool
+	SuddenDeath      bool
+	MatchUpdates     int
+	FieldUpdates     int
+	BrokenBricks     []bool
+	WaitingForPlayer bool
+}
+
+// A netConnection is a connection to another computer.
+// Reading from and writing to the network can take a long time, so we do it in
+// goroutines. That way the game does not stop while it waits for the network.
+// The goroutines talk to the game through channels.
+type netConnection struct {
+	conn net.Conn
+	// the messages we have received. The channel is closed when the
+	// connection is lost.
+	incoming chan netMessage
+	// the messages waiting to be sent
+	outgoing chan netMessage
+}
+
+// The connection to the other player. It is nil when there is no other player.
+var remotePlayer *netConnection
+
+// The host listens for new players joining, and sends them down this channel.
+var newConnections chan net.Conn
+
+// The computers that have connected to the host but have not said hello yet.
+var pendingConnections []*netConnection
+
+// The hosts listener. We need to keep it so we can close it again.
+var listener net.Listener
+
+// The host is waiting for a player to join. The game is paused until they do.
+var waitingForPlayer bool
+
+// The connection to the host has been lost.
+var connectionLost bool
+
+// A message about the network to show in the middle of the screen, like
+// "WAITING FOR A PLAYER". If it is empty nothing is shown.
+var networkStatus string
+
+// The address of the host we joined, so we can join it again if the
+// connection is lost.
+var hostAddress string
+
+// The joining computer has had the settings from the host and can draw the game.
+var haveSettings bool
+
+// The number of frames since we last sent a message to the other computer.
+var framesSinceLastMessage int
+
+// The network settings from the command line
+var hostFromCommandLine bool
+var joinFromCommandLine string
+var watchFromCommandLine string
+
+// NewNetConnection starts the goroutines that read and write the messages
+// for a connection.
+func newNetConnection(conn net.Conn) *netConnection {
+	var c *netConnection
+	c = &netConnection{}
+	c.conn = conn
+	c.incoming = make(chan netMessage, 64)
+	c.outgoing = make(chan netMessage, 64)
+	go readMessages(c)
+	go writeMessages(c)
+	return c
+}
+
+// ReadMessages reads messages from the network until the connection is lost.
+func readMessages(c *netConnection) {
+	var decoder *gob.Decoder
+	decoder = gob.NewDecoder(c.conn)
+	for {
+		var msg netMessage
+		c.conn.SetReadDeadline(time.Now().Add(NetworkTimeout))
+		if decoder.Decode(&msg) != nil {
+			// closing the channel tells the game the connection is lost
+			close(c.incoming)
+			return
+		}
+		c.incoming <- msg
+	}
+}
+
+// WriteMessages sends messages over the network until the connection is closed.
+func writeMessages(c *netConnection) {
+	var encoder *gob.Encoder
+	encoder = gob.NewEncoder(c.conn)
+	var msg netMessage
+	for msg = range c.outgoing {
+		c.conn.SetWriteDeadline(time.Now().Add(NetworkTimeout))
+		if encoder.Encode(&msg) != nil {
+			// the reader will notice the connection has gone
+			c.conn.Close()
+			return
+		}
+	}
+	c.conn.Close()
+}
+
+// SendMessage queues a message to be sent. If too many messages are already
+// waiting the connection is too slow, so we drop the message rather than
+// stopping the game.
+func sendMessage(c *netConnection, msg netMessage) {
+	select {
+	case c.outgoing <- msg:
+	default:
+	}
+}
+
+// CloseNetConnection closes a connection. The writer goroutine closes the
+// network connection once it has sent everything that was waiting.
+func closeNetConnection(c *netConnection) {
+	close(c.outgoing)
+}
+
+// WithDefaultPort adds the GamePort to an address if it does not have a port
+// already, so players can type 192.168.1.10 instead of 192.168.1.10:7777.
+func withDefaultPort(address string) string {
+	var err error
+	_, _, err = net.SplitHostPort(address)
+	if err != nil {
+		return net.JoinHostPort(address, strconv.Itoa(GamePort))
+	}
+	return address
+}
+
+// AddNetworkFlags adds the command line flags that start a network game.
+func addNetworkFlags() {
+	flag.BoolVar(&hostFromCommandLine, "host", false, "host a network game")
+	flag.StringVar(&joinFromCommandLine, "join", "", "join the network game hosted at this address, for example 192.168.1.10")
+	flag.StringVar(&watchFromCommandLine, "watch", "", "watch the network game hosted at this address")
+	flag.StringVar(&webAddress, "web", "", "host a network game that web browsers can also join at this address, for example :8080")
+	addLobbyFlags()
+}
+
+// StartNetworkGameFromCommandLine hosts or joins a network game if the
+// command line asked us to, or crashes trying.
+func startNetworkGameFromCommandLine() {
+	var err error
+	if hostFromCommandLine == true || webAddress != "" {
+		err = hostGame()
+	} else if joinFromCommandLine != "" {
+		err = joinGame(joinFromCommandLine)
+	} else if watchFromCommandLine != "" {
+		err = watchGame(watchFromCommandLine)
+	}
+	if err != nil {
+		fmt.Print("Failed to start the network game: ")
+		fmt.Println(err)
+		panic(err)
+	}
+}
+
+// ---- Hosting ----
+
+// HostGame starts listening for a player to join on the GamePort, tells the
+// other computers on the network about the game, and starts a new match that
+// is paused until a player joins.
+func hostGame() error {
+	var err error
+	err = hostGameAt(":" + strconv.Itoa(GamePort))
+	if err != nil {
+		return err
+	}
+	startAnnouncing()
+	return nil
+}
+
+// HostGameAt starts listening for a player to join at the address, without
+// telling anybody about the game, and starts a new match that is paused
+// until a player joins.
+func hostGameAt(address string) error {
+	var err error
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	newConnections = make(chan net.Conn)
+	go acceptConnections(listener, newConnections)
+	// browsers can join too, if we have been asked to serve them
+	if webAddress != "" {
+		err = startWebServer()
+		if err != nil {
+			listener.Close()
+			listener = nil
+			return err
+		}
+	}
+
+	networkRole = Hosting
+	inMenu = false
+	startNewGame()
+	waitForPlayer("WAITING FOR A PLAYER TO JOIN")
+	return nil
+}
+
+// AcceptConnections waits for computers to connect and sends them to the game.
+func acceptConnections(l net.Listener, connections chan net.Conn) {
+	for {
+		var conn net.Conn
+		var err error
+		conn, err = l.Accept()
+		if err != nil {
+			// the listener has been closed
+			return
+		}
+		connections <- conn
+	}
+}
+
+// WaitForPlayer pauses the game until a player joins.
+func waitForPlayer(status string) {
+	remotePlayer = nil
+	waitingForPlayer = true
+	paused = true
+	networkStatus = status
+}
+
+// PollHost deals with everything that has arrived from the network since the
+// last frame. It must be called once every frame.
+func pollHost() {
+	// has a computer connected? We don't know if it wants to play or watch
+	// until it says hello.
+	select {
+	case conn := <-newConnections:
+		pendingConnections = append(pendingConnections, newNetConnection(conn))
+	default:
+	}
+	pollPendingConnections()
+	pollWebClients()
+	pollSpectators()
+	if remotePlayer == nil {
+		return
+	}
+	// with rollback we don't send the state every frame, so we have to let
+	// the other player know we are still here
+	if rollbackActive == true {
+		framesSinceLastMessage = framesSinceLastMessage + 1
+		if framesSinceLastMessage >= UpdatesPerSecond {
+			sendMessage(remotePlayer, netMessage{Kind: MsgPing, Spectators: spectatorCount})
+			framesSinceLastMessage = 0
+		}
+	}
+	for {
+		select {
+		case msg, ok := <-remotePlayer.incoming:
+			if !ok {
+				// the connection has been lost. Pause the game until the player
+				// joins again.
+				closeNetConnection(remotePlayer)
+				stopRollbackSession()
+				waitForPlayer("CONNECTION LOST - WAITING FOR THE PLAYER TO JOIN AGAIN")
+				return
+			}
+			handleMessageFromPlayer(msg)
+		default:
+			return
+		}
+	}
+}
+
+// PollPendingConnections waits for the computers that have just connected to
+// say hello.
+func pollPendingConnections() {
+	var stillPending []*netConnection
+	var c *netConnection
+	for _, c = range pendingConnections {
+		select {
+		case msg, ok := <-c.incoming:
+			if !ok {
+				// it went away before it said hello
+				closeNetConnection(c)
+			} else if msg.Kind != MsgHello {
+				rejectConnection(c, "SAY HELLO FIRST")
+			} else {
+				handleHello(c, msg)
+			}
+		default:
+			stillPending = append(stillPending, c)
+		}
+	}
+	pendingConnections = stillPending
+}
+
+// HandleHello decides what to do with a computer that has said hello. It can
+// watch the game, or play it if nobody else is playing.
+func handleHello(c *netConnection, msg netMessage) {
+	if msg.Version != NetworkProtocolVersion {
+		rejectConnection(c, "THE HOST IS RUNNING A DIFFERENT VERSION OF PONG")
+		return
+	}
+	if msg.Spectator == true {
+		addSpectator(c)
+		return
+	}
+	if remotePlayer != nil || webPlayer != nil {
+		rejectConnection(c, "THE GAME IS FULL - YOU CAN WATCH IT INSTEAD")
+		return
+	}
+	// the player has joined, so we can start playing
+	remotePlayer = c
+	waitingForPlayer = false
+	paused = false
+	networkStatus = ""
+	if netcode == NetcodeRollback {
+		startRollbackWithPlayer(msg.UDPPort)
+		return
+	}
+	sendMessage(remotePlayer, netMessage{Kind: MsgSettings, Settings: makeNetSettings()})
+}
+
+// RejectConnection tells a computer why it cannot join, and closes the connection.
+func rejectConnection(c *netConnection, reason string) {
+	sendMessage(c, netMessage{Kind: MsgRejected, Reason: reason})
+	closeNetConnection(c)
+}
+
+// HandleMessageFromPlayer deals with one message from the joining player.
+func handleMessageFromPlayer(msg netMessage) {
+	switch msg.Kind {
+	case MsgInput:
+		handleRemoteInput(msg.Input)
+	case MsgPing:
+		// nothing to do, the message just tells us the player is still there
+	}
+}
+
+// StartRollbackWithPlayer sends the joining player the settings and the game
+// state, and starts playing with rollback.
+func startRollbackWithPlayer(playersUDPPort int) {
+	var port int
+	var err error
+	port, err = openRollbackConn()
+	if err != nil || playersUDPPort == 0 {
+		rejectConnection(remotePlayer, "THE HOST COULD NOT START ROLLBACK")
+		closeRollbackConn()
+		waitForPlayer("WAITING FOR A PLAYER TO JOIN")
+		return
+	}
+	var settings *netSettings
+	settings = makeNetSettings()
+	settings.Netcode = NetcodeRollback
+	settings.UDPPort = port
+	var state gameState
+	state = saveGameState()
+	settings.StartState = &state
+	sendMessage(remotePlayer, netMessage{Kind: MsgSettings, Settings: settings})
+
+	var peer net.UDPAddr
+	peer.IP = remotePlayer.conn.RemoteAddr().(*net.TCPAddr).IP
+	peer.Port = playersUDPPort
+	startRollbackSession(&peer, Player)
+}
+
+// HandleRemoteInput moves the computers bat when the joining player presses a
+// key. It follows the same rules as the keys on this computer.
+func handleRemoteInput(input int) {
+	// with rollback the inputs arrive over UDP instead
+	if rollbackActive == true {
+		return
+	}
+	if waitingForPlayer == true || gameOver == true {
+		return
+	}
+	if input == InputPause {
+		paused = !paused
+		return
+	}
+	if paused == true {
+		return
+	}
+	if input == InputUp {
+		computersBatY = computersBatY - computersBatStep()
+		if computersBatY < 0 {
+			computersBatY = 0
+		}
+	} else if input == InputDown {
+		computersBatY = computersBatY + computersBatStep()
+		if computersBatY+computersBatH > windowHeight {
+			computersBatY = windowHeight - computersBatH
+		}
+	}
+}
+
+// SendStateToRemotePlayer sends where everything is to the joining player.
+// It must be called once every frame.
+func sendStateToRemotePlayer() {
+	// with rollback the other computer works out the state for itself
+	if remotePlayer == nil || rollbackActive == true {
+		return
+	}
+	sendMessage(remotePlayer, netMessage{Kind: MsgState, State: makeNetState(), Spectators: spectatorCount})
+}
+
+// MakeNetSettings collects the settings for the match so we can send them.
+func makeNetSettings() *netSettings {
+	var s netSettings
+	s.GameMode = gameMode
+	s.ArenaNumber = arenaNumber
+	s.Gravity = gravity
+	s.Wind = wind
+	s.TimeLimit = timeLimit
+	s.MyBatH = myBatH
+	s.ComputersBatH = computersBatH
+	s.MyBatSpeed = myBatSpeed
+	s.ComputersBatSpeed = computersBatSpeed
+	s.MyTargetScore = myTargetScore
+	s.ComputersTargetScore = computersTargetScore
+	s.BrickLayout = brickLayout
+	s.Difficulty = difficulty
+	s.BallSpeed = ballSpeed
+	s.ComputerSpeed = computerSpeed
+	return &s
+}
+
+// MakeNetState collects where everything is so we can send it.
+func makeNetState() *netState {
+	var s netState
+	s.MyBatY = myBatY
+	s.ComputersBatY = computersBatY
+	s.BallX = ballX
+	s.BallY = ballY
+	s.MyScore = myScore
+	s.ComputersScore = computersScore
+	s.Paused = paused
+	s.GameOver = gameOver
+	s.SuddenDeath = suddenDeath
+	s.MatchUpdates = matchUpdates
+	s.FieldUpdates = fieldUpdates
+	s.WaitingForPlayer = waitingForPlayer
+	var i int
+	for i = 0; i < len(bricks); i++ {
+		s.BrokenBricks = append(s.BrokenBricks, bricks[i].broken)
+	}
+	return &s
+}
+
+// ---- Joining ----
+
+// JoinGame connects to a host to play the game.
+func joinGame(address string) error {
+	return connectToHost(address, false)
+}
+
+// ConnectToHost connects to a host and says hello. The game is drawn once the
+// host has sent the settings for the match.
+func connectToHost(address string, spectate bool) error {
+	var conn net.Conn
+	var err error
+	address = withDefaultPort(address)
+	conn, err = net.DialTimeout("tcp", address, NetworkTimeout)
+	if err != nil {
+		return err
+	}
+	hostAddress = address
+	var hello netMessage
+	hello.Kind = MsgHello
+	hello.Version = NetworkProtocolVersion
+	hello.Spectator = spectate
+	// a dedicated server also needs to know which lobby we want
+	hello.Lobby = lobbyName
+	hello.CreateLobby = createLobby
+	if spectate == false {
+		// open a UDP port in case the host wants to use rollback
+		hello.UDPPort, err = openRollbackConn()
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	remotePlayer = newNetConnection(conn)
+	sendMessage(remotePlayer, hello)
+
+	if spectate == true {
+		networkRole = Spectating
+		networkStatus = "CONNECTING TO THE GAME"
+	} else {
+		networkRole = Joined
+		networkStatus = "JOINING THE GAME"
+	}
+	inMenu = false
+	haveSettings = false
+	connectionLost = false
+	paused = true
+	delayedStates = nil
+	return nil
+}
+
+// PollClient deals with everything the host has sent since the last frame.
+// It must be called once every frame.
+func pollClient() {
+	if remotePlayer == nil {
+		return
+	}
+	// let the host know we are still here
+	framesSinceLastMessage = framesSinceLastMessage + 1
+	if framesSinceLastMessage >= UpdatesPerSecond {
+		sendToHost(netMessage{Kind: MsgPing})
+	}
+	// spectators draw the game a little behind the players
+	if networkRole == Spectating {
+		applyDelayedState()
+	}
+	for {
+		select {
+		case msg, ok := <-remotePlayer.incoming:
+			if !ok {
+				loseConnectionToHost("CONNECTION LOST - RETURN TO JOIN AGAIN, ESCAPE FOR THE MENU")
+				return
+			}
+			handleMessageFromHost(msg)
+			// the host might have turned us away, which closes the
+			// connection
+			if remotePlayer == nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// HandleMessageFromHost deals with one message from the host.
+func handleMessageFromHost(msg netMessage) {
+	if msg.Kind == MsgState || msg.Kind == MsgPing {
+		spectatorCount = msg.Spectators
+	}
+	switch msg.Kind {
+	case MsgRejected:
+		loseConnectionToHost(msg.Reason + " - ESCAPE FOR THE MENU")
+	case MsgSettings:
+		if msg.Settings != nil && checkNetSettings(*msg.Settings) != nil {
+			loseConnectionToHost("THE HOST'S RULES DON'T MAKE SENSE - ESCAPE FOR THE MENU")
+		} else if msg.Settings != nil {
+			applyNetSettings(msg.Settings)
+			haveSettings = true
+			networkStatus = ""
+			// if we created a lobby on a server it exists now, so if we
+			// have to join again we must not try to create it again
+			createLobby = false
+			if msg.Settings.Netcode == NetcodeRollback && msg.Settings.StartState != nil {
+				loadGameState(*msg.Settings.StartState)
+				var peer net.UDPAddr
+				peer.IP = remotePlayer.conn.RemoteAddr().(*net.TCPAddr).IP
+				peer.Port = msg.Settings.UDPPort
+				startRollbackSession(&peer, Computer)
+			} else {
+				closeRollbackConn()
+			}
+		}
+	case MsgState:
+		if msg.State != nil && haveSettings == true {
+			if networkRole == Spectating {
+				delayState(msg.State)
+			} else {
+				applyNetState(msg.State)
+			}
+		}
+	}
+}
+
+// LoseConnectionToHost closes the connection to the host and pauses the game.
+func loseConnectionToHost(status string) {
+	stopRollbackSession()
+	closeNetConnection(remotePlayer)
+	remotePlayer = nil
+	connectionLost = true
+	paused = true
+	networkStatus = status
+}
+
+// SendToHost sends a message to the host.
+func sendToHost(msg netMessage) {
+	if remotePlayer == nil {
+		return
+	}
+	sendMessage(remotePlayer, msg)
+	framesSinceLastMessage = 0
+}
+
+// CheckNetSettings makes sure the rules for a match make sense before they are
+// used. The rules might come from a saved match, a replay or another
+// computer, and applyNetSettings would crash on an arena that doesn't exist.
+func checkNetSettings(s netSettings) error {
+	if s.GameMode != ClassicMode && s.GameMode != BreakoutMode {
+		return errors.New("the game mode is unknown")
+	}
+	if s.ArenaNumber < 0 || s.ArenaNumber >= len(arenas) {
+		return errors.New("the arena is unknown")
+	}
+	if s.Difficulty < 0 || s.Difficulty >= len(difficultyNames) {
+		return errors.New("the difficulty is unknown")
+	}
+	if s.TimeLimit < 0 {
+		return errors.New("the time limit is negative")
+	}
+	if s.MyBatH < MinimumBatHeight || s.ComputersBatH < MinimumBatHeight ||
+		s.MyBatH > FieldHeight || s.ComputersBatH > FieldHeight {
+		return errors.New("a bat is the wrong size")
+	}
+	if s.MyBatSpeed <= 0 || s.ComputersBatSpeed <= 0 {
+		return errors.New("a bat can't move")
+	}
+	if s.BallSpeed < MinimumBallSpeed || s.BallSpeed > MaximumBallSpeed ||
+		s.ComputerSpeed < MinimumComputerSpeed || s.ComputerSpeed > MaximumComputerSpeed {
+		return errors.New("the ball or the computer goes too fast or too slow")
+	}
+	if s.MyTargetScore < 1 || s.MyTargetScore > HighestScore ||
+		s.ComputersTargetScore < 1 || s.ComputersTargetScore > HighestScore {
+		return errors.New("a target score can't be shown")
+	}
+	return nil
+}
+
+// ApplyNetSettings sets up the match the same way the host has set it up.
+func applyNetSettings(s *netSettings) {
+	gameMode = s.GameMode
+	arenaNumber = s.ArenaNumber
+	gravity = s.Gravity
+	wind = s.Wind
+	zones = arenas[arenaNumber].zones
+	timeLimit = s.TimeLimit
+	myBatH = s.MyBatH
+	computersBatH = s.ComputersBatH
+	myBatSpeed = s.MyBatSpeed
+	computersBatSpeed = s.ComputersBatSpeed
+	myTargetScore = s.MyTargetScore
+	computersTargetScore = s.ComputersTargetScore
+	brickLayout = s.BrickLayout
+	difficulty = s.Difficulty
+	ballSpeed = s.BallSpeed
+	computerSpeed = s.ComputerSpeed
+	bricks = nil
+	if gameMode == BreakoutMode {
+		buildBricks(brickLayout)
+	}
+	initialiseMyBatPosition()
+	initialiseComputersBatPosition()
+}
+
+// ApplyNetState moves everything to where the host says it is.
+func applyNetState(s *netState) {
+	myBatY = s.MyBatY
+	computersBatY = s.ComputersBatY
+	ballX = s.BallX
+	ballY = s.BallY
+	myScore = s.MyScore
+	computersScore = s.ComputersScore
+	paused = s.Paused
+	gameOver = s.GameOver
+	suddenDeath = s.SuddenDeath
+	matchUpdates = s.MatchUpdates
+	fieldUpdates = s.FieldUpdates
+	var i int
+	for i = 0; i < len(bricks) && i < len(s.BrokenBricks); i++ {
+		bricks[i].broken = s.BrokenBricks[i]
+	}
+	if s.WaitingForPlayer == true {
+		networkStatus = "THE HOST IS WAITING"
+	} else if paused == true {
+		networkStatus = "PAUSED"
+	} else {
+		networkStatus = ""
+	}
+}
+
+// ---- Both ----
+
+// PollNetwork deals with everything that has arrived from the network.
+func pollNetwork() {
+	switch networkRole {
+	case Hosting:
+		pollHost()
+	case Joined, Spectating:
+		pollClient()
+	}
+}
+
+// LeaveNetworkGame closes all the network connections and goes back to the menu.
+func leaveNetworkGame() {
+	if remotePlayer != nil {
+		closeNetConnection(remotePlayer)
+		remotePlayer = nil
+	}
+	if listener != nil {
+		listener.Close()
+		listener = nil
+	}
+	var c *netConnection
+	for _, c = range pendingConnections {
+		closeNetConnection(c)
+	}
+	pendingConnections = nil
+	closeSpectators()
+	stopWebServer()
+	delayedStates = nil
+	stopAnnouncing()
+	stopRollbackSession()
+	networkRole = NotNetworked
+	networkStatus = ""
+	waitingForPlayer = false
+	connectionLost = false
+	inMenu = true
+	startNewGame()
+}
+
+// HandleNetworkGameEvent responds to the keys pressed during a network game,
+// for the keys that do something different from a normal game. It returns
+// true if it dealt with the key.
+func handleNetworkGameEvent(event sdl.Event) bool {
+	if isKeyDownEvent(event) == false {
+		return false
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	switch networkRole {
+	case Hosting:
+		if keyDownEvt.Keysym.Sym == sdl.K_ESCAPE {
+			leaveNetworkGame()
+			return true
+		}
+		// the host cannot unpause the game while it waits for a player
+		if keyDownEvt.Keysym.Sym == sdl.K_PAUSE && waitingForPlayer == true {
+			return true
+		}
+	case Joined, Spectating:
+		switch keyDownEvt.Keysym.Sym {
+		case sdl.K_ESCAPE:
+			leaveNetworkGame()
+		case sdl.K_RETURN:
+			if connectionLost == true {
+				var err error
+				err = connectToHost(hostAddress, networkRole == Spectating)
+				if err != nil {
+					networkStatus = "COULD NOT JOIN - RETURN TO TRY AGAIN, ESCAPE FOR THE MENU"
+				}
+			}
+		case sdl.K_UP, sdl.K_DOWN, sdl.K_PAUSE:
+			// spectators can only watch
+			if networkRole == Spectating {
+				return true
+			}
+			// with rollback these keys are read by the rollback code
+			if rollbackActive == true {
+				return false
+			}
+			sendInputToHost(keyDownEvt.Keysym.Sym)
+		}
+		// the joining computer never moves anything itself
+		return true
+	}
+	return false
+}
+
+// SendInputToHost tells the host which key our player pressed.
+func sendInputToHost(key sdl.Keycode) {
+	switch key {
+	case sdl.K_UP:
+		sendToHost(netMessage{Kind: MsgInput, Input: InputUp})
+	case sdl.K_DOWN:
+		sendToHost(netMessage{Kind: MsgInput, Input: InputDown})
+	case sdl.K_PAUSE:
+		sendToHost(netMessage{Kind: MsgInput, Input: InputPause})
+	}
+}
+
+// RenderNetworkStatus shows the network message in the middle of the screen.
+func renderNetworkStatus() {
+	if networkStatus == "" {
+		return
+	}
+	renderTextCentred(networkStatus, windowWidth/2, windowHeight/2+80, 2, 255, 255, 0)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// WaitForMessage waits for the next message of the given kind from the
+// other computer, skipping any others.
+func waitForMessage(t *testing.T, c *netConnection, kind int) netMessage {
+	var timeout <-chan time.Time
+	timeout = time.After(NetworkTimeout)
+	for {
+		select {
+		case msg, ok := <-c.incoming:
+			if !ok {
+				t.Fatal("the connection was closed")
+			}
+			if msg.Kind == kind {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("no message of kind %d arrived", kind)
+		}
+	}
+}
+
+// HostTestGame hosts a game that only this computer can join, on any free
+// port, without announcing it. It returns the address to join.
+func hostTestGame(t *testing.T) string {
+	webAddress = ""
+	var err error
+	err = hostGameAt("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(leaveNetworkGame)
+	return listener.Addr().String()
+}
+
+// PollHostUntil polls the host until done is true, or it takes too long.
+func pollHostUntil(done func() bool) {
+	var deadline time.Time
+	deadline = time.Now().Add(NetworkTimeout)
+	for done() == false && time.Now().Before(deadline) {
+		pollHost()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// FakeTestHost listens like a host, and hands the test the connection of the
+// first computer that says hello, so the test can play the host's part.
+func fakeTestHost(t *testing.T) (string, chan *netConnection) {
+	var l net.Listener
+	var err error
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	var joined chan *netConnection
+	joined = make(chan *netConnection, 1)
+	go func() {
+		var conn net.Conn
+		var err error
+		conn, err = l.Accept()
+		if err != nil {
+			return
+		}
+		var c *netConnection
+		c = newNetConnection(conn)
+		msg, ok := <-c.incoming
+		if ok && msg.Kind == MsgHello {
+			joined <- c
+		}
+	}()
+	return l.Addr().String(), joined
+}
+
+// JoinFakeHost joins a fake host, and returns the host's end of the
+// connection. The test must close it.
+func joinFakeHost(t *testing.T) *netConnection {
+	var address string
+	var joined chan *netConnection
+	address, joined = fakeTestHost(t)
+	var err error
+	err = joinGame(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(leaveNetworkGame)
+	select {
+	case c := <-joined:
+		return c
+	case <-time.After(NetworkTimeout):
+		t.Fatal("the player never said hello")
+	}
+	return nil
+}
+
+// PollClientUntil polls the joining computer until done is true, or it takes
+// too long.
+func pollClientUntil(done func() bool) {
+	var deadline time.Time
+	deadline = time.Now().Add(NetworkTimeout)
+	for done() == false && time.Now().Before(deadline) {
+		pollClient()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestHostAndJoin hosts a game and joins it from the same computer. Both ends
+// share the game's variables, so the test keeps the joining computer's
+// connection to itself and then carries on as the host.
+func TestHostAndJoin(t *testing.T) {
+	setUpTestMatch(t)
+	var address string
+	address = hostTestGame(t)
+	if waitingForPlayer == false || paused == false {
+		t.Fatal("the host didn't wait for a player")
+	}
+
+	var err error
+	err = joinGame(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var joined *netConnection
+	joined = remotePlayer
+	defer closeNetConnection(joined)
+	networkRole = Hosting
+	remotePlayer = nil
+
+	// the host notices the new connection, and the hello that follows it
+	pollHostUntil(func() bool { return waitingForPlayer == false })
+	if waitingForPlayer == true || remotePlayer == nil {
+		t.Fatal("the host never saw the player say hello")
+	}
+
+	// the player is sent the settings for the match, which must make sense
+	var msg netMessage
+	msg = waitForMessage(t, joined, MsgSettings)
+	if msg.Settings == nil {
+		t.Fatal("the settings message has no settings")
+	}
+	err = checkNetSettings(*msg.Settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Settings.BallSpeed != ballSpeed || msg.Settings.MyBatH != myBatH {
+		t.Errorf("the player was sent different settings: %+v", *msg.Settings)
+	}
+
+	// the player's keys move the right bat on the host
+	var before int
+	before = computersBatY
+	sendMessage(joined, netMessage{Kind: MsgInput, Input: InputUp})
+	pollHostUntil(func() bool { return computersBatY != before })
+	if computersBatY != before-computersBatStep() {
+		t.Errorf("the bat moved from %d to %d", before, computersBatY)
+	}
+
+	// and the player is told where everything is
+	sendStateToRemotePlayer()
+	msg = waitForMessage(t, joined, MsgState)
+	if msg.State == nil || msg.State.ComputersBatY != computersBatY {
+		t.Errorf("the player was sent the wrong state: %+v", msg.State)
+	}
+}
+
+// TestJoinDifferentVersion checks the host turns away a computer running a
+// different version of the game.
+func TestJoinDifferentVersion(t *testing.T) {
+	setUpTestMatch(t)
+	var address string
+	address = hostTestGame(t)
+	var conn net.Conn
+	var err error
+	conn, err = net.DialTimeout("tcp", address, NetworkTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c *netConnection
+	c = newNetConnection(conn)
+	defer closeNetConnection(c)
+	sendMessage(c, netMessage{Kind: MsgHello, Version: NetworkProtocolVersion + 1})
+	pollHostUntil(func() bool { return len(pendingConnections) > 0 })
+	pollHostUntil(func() bool { return len(pendingConnections) == 0 })
+	var msg netMessage
+	msg = waitForMessage(t, c, MsgRejected)
+	if msg.Reason == "" {
+		t.Error("the rejection doesn't say why")
+	}
+	if remotePlayer != nil || waitingForPlayer == false {
+		t.Error("the host let the player join")
+	}
+}
+
+// TestJoinRejected checks a joining computer that is turned away tells the
+// player why, instead of crashing.
+func TestJoinRejected(t *testing.T) {
+	setUpTestMatch(t)
+	var host *netConnection
+	host = joinFakeHost(t)
+	rejectConnection(host, "THE GAME IS FULL")
+	pollClientUntil(func() bool { return remotePlayer == nil })
+	if remotePlayer != nil || connectionLost == false {
+		t.Fatal("the player didn't notice they were turned away")
+	}
+	if strings.HasPrefix(networkStatus, "THE GAME IS FULL") == false {
+		t.Errorf("the player was told %q", networkStatus)
+	}
+}
+
+// TestJoinBadSettings checks a joining computer leaves a host whose rules
+// don't make sense, instead of crashing.
+func TestJoinBadSettings(t *testing.T) {
+	setUpTestMatch(t)
+	var host *netConnection
+	host = joinFakeHost(t)
+	var settings *netSettings
+	settings = makeNetSettings()
+	settings.ArenaNumber = len(arenas)
+	sendMessage(host, netMessage{Kind: MsgSettings, Settings: settings})
+	defer closeNetConnection(host)
+	pollClientUntil(func() bool { return remotePlayer == nil })
+	if remotePlayer != nil || haveSettings == true {
+		t.Fatal("the player used rules that don't make sense")
+	}
+}
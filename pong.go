@@ -4,9 +4,14 @@ import (
 	// This is the graphics library we are going to use. It is called the
 	// Simple Direct Media Library. SDL for short. We need this to create the
 	// window and to provide the drawing functions we need.
+	"errors"
+	"flag"
 	"fmt"
 	"math"
+	"os"
+	"runtime"
 	"strconv"
+	"unsafe"
 
 	"github.com/gophercoders/random"
 	"github.com/veandco/go-sdl2/sdl"
@@ -23,8 +28,9 @@ var window *sdl.Window
 // that actually does the drawing
 var renderer *sdl.Renderer
 
-// These variabels are important. They are the width and height of the window
-// If you change these you will change the size of the image
+// These variabels are important. They are the width and height of the playing
+// field. The game is drawn on the playing field, and then the playing field is
+// stretched to fit the window, whatever size the window is (see window.go).
 var windowWidth int
 var windowHeight int
 
@@ -55,17 +61,36 @@ var gameOverGfx *sdl.Texture
 
 // ---- Game State variables ----
 
-// the balls speed in pixels per second
+// The balls speed, the computers bat speed and the number of points a player
+// has to score to win the game are settings now. They are in config.go.
+
+// This is the highest score we can show. We only have score graphics up to
+// 11, so nobody can need more than 11 points to win.
 // This should never change during the game. We can make sure of this
 // if we use define a constant value. Go provents us form changing the
 // value of a constant - it's an illegal action - it breaks the rile of go.
-const BallSpeed = 550
+const HighestScore = 11
+
+// The game modes. The game mode decides which rules the game is played by.
+// In the classic mode the players just hit the ball back and forth.
+// In the breakout mode there is a wall of bricks in the middle of the
+// playing field as well.
+const ClassicMode = 0
+const BreakoutMode = 1
 
-// This is the speed of the computers bat, in pixels per second
-const ComputersBatSpeed = 350
+// The game mode we are playing. The game starts in the classic mode unless
+// the breakout mode is chosen on the command line.
+var gameMode int
 
-// This is the number of points a player has to score to win the game
-const WinningScore = 11
+// The players who can hit the ball. We use these to remember who hit the
+// ball last.
+const NoOne = 0
+const Player = 1
+const Computer = 2
+
+// The player who last hit the ball with their bat. When the ball is served
+// nobody has hit it yet.
+var lastHitBy int
 
 // The quit flag this is used to control the main game loop.
 // If quit is true then the user wants to finish the game. This will
@@ -78,7 +103,7 @@ var quit bool
 var paused bool
 
 // The game over flag controls how the game ends.
-// If a players score is equal to te WinningScore the game is over so
+// If a players score is equal to their target score the game is over so
 // the game over flag is true. When the game over flag is true the AI stops
 // the ball stops moving, and the player cannot move or pause the game.
 // The game over game over flag starts as false
@@ -143,6 +168,35 @@ var gameOverH int
 
 // The programs main function
 func main() {
+	// "pong server" runs the dedicated server instead of the game. The server
+	// has no window, so it doesn't need any of the graphics setup.
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		parseServerCommandLine(os.Args[2:])
+		runServer()
+		return
+	}
+	// "pong replay <file>" plays a recorded match
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if len(os.Args) < 3 {
+			fmt.Println("Which replay? For example: pong replay match.replay")
+			return
+		}
+		replayFilename = os.Args[2]
+		os.Args = append(os.Args[:1], os.Args[3:]...)
+	}
+	// read the settings the user gave us on the command line
+	parseCommandLine()
+	// list the lobbies on a server, if that is all the user wants
+	if listLobbiesFrom != "" {
+		listLobbies(listLobbiesFrom)
+		return
+	}
+	// load the replay before we open the window, so we can stop if it is
+	// from a different version of pong
+	if loadReplayFromCommandLine() == false {
+		return
+	}
+
 	// ---- This is the start of Owen's graphics setup code ----
 
 	// First we have to initalise the SDL library, before we can use it
@@ -152,14 +206,16 @@ func main() {
 	// the program exits for us. We don't have to remember to put this at the end!
 	defer sdl.Quit()
 
-	// if you want to change these try 800 for the width and 600 for the height
-	windowWidth = 1024
-	windowHeight = 768
+	// The playing field is always the same size. The window starts at the
+	// size in the settings. If you want to change it try -width 800 -height
+	// 600 on the command line, or just drag the edge of the window.
+	windowWidth = FieldWidth
+	windowHeight = FieldHeight
 
 	// Now we have to create the window we want to use.
 	// We need to tell the SDL library how big to make the window of the correct
 	// size - that's what the bit in the brackets does
-	window = createWindow(windowWidth, windowHeight)
+	window = createWindow(startWindowWidth, startWindowHeight)
 	// automatically destroy the window when the program finishes
 	defer window.Destroy()
 	// Now we have a window we need to create a renderer so we can draw into
@@ -168,6 +224,9 @@ func main() {
 	renderer = createRenderer(window)
 	// automatically destroy the renderer when the program exits.
 	defer renderer.Destroy()
+	// everything is drawn on the playing field, not straight into the window
+	createPlayfield()
+	defer playfield.Destroy()
 
 	// Set a black i.e. RGBA (0,0,0,0) background colour and clear the window
 	renderer.SetDrawColor(0, 0, 0, 0)
@@ -178,12 +237,105 @@ func main() {
 	defer cleanup()
 	// initialise the games variables.
 	initialise()
+	// host or join a network game if the command line asked us to
+	startNetworkGameFromCommandLine()
+	// or play a replay
+	if startPlaybackFromCommandLine() == false {
+		return
+	}
+	// turning a replay into pictures doesn't need the game loop
+	if exportFilename != "" {
+		exportReplay()
+		return
+	}
+	startCaptureFromCommandLine()
 	// render everything initially so that we can see the game before it starts
 	render()
 	// now start the main game loop of the game.
 	gameMainLoop()
 }
 
+// The game mode typed on the command line.
+var modeName string
+
+// ParseCommandLine reads the settings the user typed after the programs name
+// on the command line. For example
+//
+//	pong -mode breakout -bricks mylayout.txt
+//
+// starts the game in breakout mode with the bricks from mylayout.txt.
+// Settings that aren't on the command line can come from the environment or
+// the settings file instead (see config.go). If a setting doesn't make sense
+// we say which one, and where it came from, and stop.
+func parseCommandLine() {
+	flag.StringVar(&modeName, "mode", "classic", "the game mode to play: classic or breakout")
+	flag.StringVar(&brickLayoutFilename, "bricks", "", "the file to load the brick layout from in breakout mode")
+	flag.DurationVar(&timeLimit, "time", 0, "the length of a timed match, for example 3m. Zero means the match is not timed")
+	addHandicapFlags()
+	addFieldFlags()
+	addNetworkFlags()
+	addRollbackFlags()
+	addReplayFlags()
+	addInstantReplayFlags()
+	addCaptureFlags()
+	addScreenshotFlags()
+	addDifficultyFlags()
+	addConfigFlags()
+	addWindowFlags()
+	addAssetFlags()
+	addThemeFlags()
+	addGraphicsFlags()
+	addEffectsFlags()
+	addTrailFlags()
+	flag.Parse()
+
+	var err error
+	err = loadSettings()
+	if err == nil {
+		err = checkSettings()
+	}
+	if err != nil {
+		fmt.Print("Bad setting: ")
+		fmt.Println(err)
+		fmt.Println("Type pong -help to see all the settings.")
+		os.Exit(2)
+	}
+}
+
+// CheckSettings makes sure the settings make sense. The settings added in
+// config.go are checked first, because the handicaps need the winning score.
+func checkSettings() error {
+	var checks []func() error
+	checks = []func() error{checkConfigFlags, checkWindowFlags, checkAssetFlags, checkGraphicsFlags,
+		checkThemeFlags, checkTrailFlags, checkModeFlags, checkHandicaps, checkFieldFlags,
+		checkRollbackFlags, checkCaptureFlags, checkDifficultyFlag}
+	var check func() error
+	for _, check = range checks {
+		var err error
+		err = check()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckModeFlags works out the game mode, and checks the time limit.
+func checkModeFlags() error {
+	switch modeName {
+	case "classic":
+		gameMode = ClassicMode
+	case "breakout":
+		gameMode = BreakoutMode
+	default:
+		return badSetting("mode", "there is no game mode called "+modeName)
+	}
+	if timeLimit < 0 {
+		return badSetting("time", "the time limit cannot be less than zero")
+	}
+	return nil
+}
+
 // Initialise sets the inital values of the game state variables.
 // Initialise must be called before the games main loop starts.
 func initialise() {
@@ -193,24 +345,72 @@ func initialise() {
 	paused = false
 	// initially the gmae is not over
 	gameOver = false
-	// The scores start at zero
-	myScore = 0
-	computersScore = 0
 	// load the game graphics
 	loadGraphics()
+	// load the pictures for the theme chosen in the settings
+	useTheme()
+	initialiseScorePositions()
+	initialiseGameOverPosition()
+	// load the players' profiles, so the menu can show who is playing
+	loadProfiles()
+	// look for a match that was saved when the game was closed
+	checkForSavedMatch()
+	if haveSavedMatch == false {
+		menuSelection = MenuMode
+	}
+	// The game starts with the menu, so the players can choose how to play.
+	// When they have chosen the menu calls startNewGame.
+	inMenu = true
+	startNewGame()
+}
+
+// StartNewGame puts everything back to how it is at the start of a match.
+func startNewGame() {
+	resetMatch()
+	// start keeping track of the match for the statistics and the records
+	startMatchStatistics()
+	startMatchRecord()
+	// forget the last match's rally
+	clearRallyFrames()
+	stopInstantReplay()
+	// the last match's confetti and the ball's trail have gone
+	clearParticles()
+	clearBallTrail()
+	// record the match if we have been asked to
+	startRecording()
+}
+
+// ResetMatch puts the game state (see state.go) back to how it is at the
+// start of a match, and nothing else. The server uses it for the matches in
+// its lobbies, which don't have their own statistics, records or effects.
+func resetMatch() {
+	paused = false
+	gameOver = false
+	// The scores start at zero, unless a player has a handicap
+	myScore = myStartingScore
+	computersScore = computersStartingScore
+	// nobody has hit the ball yet
+	lastHitBy = NoOne
+	// pick a new seed for the serve random number generator, so every match
+	// is different
+	seedServeRandom(uint64(random.GetRandomNumberInRange(1, math.MaxInt32)))
 	initialiseMyBatPosition()
 	initialiseComputersBatPosition()
 	initialiseBallPosition()
 	initialiseBallDirection()
-	initialiseScorePositions()
-	initialiseGameOverPosition()
+	initialiseClock()
+	initialiseFieldModifiers()
+	// in breakout mode we need to build the wall of bricks
+	if gameMode == BreakoutMode {
+		initialiseBricks()
+	}
 }
 
 func initialiseBallDirection() {
 	// pick some random numbers to determine if the ball will move up or down
 	// and left or right initially.
 	var n int
-	n = random.GetRandomNumberInRange(1, 10)
+	n = serveRandomNumber(1, 10)
 	var up bool
 	if isOddNumber(n) {
 		up = true // we want the ball to move up - decreasing Y coordinate
@@ -218,7 +418,7 @@ func initialiseBallDirection() {
 		up = false // we want the ball to move down - increasing y coordinate
 	}
 
-	n = random.GetRandomNumberInRange(1, 10)
+	n = serveRandomNumber(1, 10)
 	var left bool
 	if isOddNumber(n) {
 		left = true // we want the ball to move left - decreasing X coordiiate
@@ -226,8 +426,8 @@ func initialiseBallDirection() {
 		left = false //we want the ball to move right - increasing X coordinate
 	}
 	// pick two random mumbers for the initial direction
-	ballDirX = float64(random.GetRandomNumberInRange(1, 10))
-	ballDirY = float64(random.GetRandomNumberInRange(1, 10))
+	ballDirX = float64(serveRandomNumber(1, 10))
+	ballDirY = float64(serveRandomNumber(1, 10))
 	// are we moving left?
 	if left {
 		ballDirX = ballDirX * -1
@@ -252,8 +452,8 @@ func setBallDirection(newDirectionX, newDirectionY float64) {
 	// We want to keep the balls speed constant so
 	// the balls new position (in each direction) is the balls speed (in each direction)
 	// multiplied by _scalled_ new direction (in each direction)
-	ballDirX = BallSpeed * (newDirectionX / length)
-	ballDirY = BallSpeed * (newDirectionY / length)
+	ballDirX = float64(ballSpeed) * (newDirectionX / length)
+	ballDirY = float64(ballSpeed) * (newDirectionY / length)
 }
 
 func isOddNumber(number int) bool {
@@ -272,17 +472,108 @@ func isOddNumber(number int) bool {
 // on the user input and the rules of the game. The final task is to update, or
 // render, the changes to the screen.
 func gameMainLoop() {
+	lastUpdateTicks = sdl.GetTicks()
 	for quit == false {
-		getInput()
-		// if the game is not paused, then we must update the games state
-		if paused == false {
-			updateState()
+		// the game is updated UpdatesPerSecond times a second, however many
+		// frames are drawn, so the frame rate doesn't change how fast the
+		// game goes. A fast computer might draw a frame without updating the
+		// game, and a slow one might update it a few times between frames.
+		var updates, i int
+		updates = updatesDue()
+		for i = 0; i < updates && quit == false; i++ {
+			updateGame()
 		}
+		updateParticles()
 		render()
 	}
 }
 
+// UpdateGame reads the players' keys and updates the game once. It is called
+// UpdatesPerSecond times a second.
+func updateGame() {
+	// remember whether the instant replay was on the screen at the start of
+	// this update, because updateState can start one
+	var replayingPoint bool
+	replayingPoint = showingInstantReplay
+	getInput()
+	// deal with any messages from the network
+	pollNetwork()
+	if inNetworkScreen == true {
+		pollDiscoveredGames()
+	}
+	// if the game is not paused, and the menu is not on the screen, then
+	// we must update the games state. If we have joined a network game
+	// the host updates the games state for us, unless we are using
+	// rollback. With rollback both computers update the game state.
+	// Spectators never update the game state.
+	if playingBack == true {
+		updatePlayback()
+	} else if showingInstantReplay == true {
+		updateInstantReplay()
+	} else if rollbackActive == true {
+		rollbackTick()
+	} else if paused == false && inMenu == false && inNetworkScreen == false &&
+		inTournamentScreen == false && networkRole != Joined && networkRole != Spectating {
+		updateState()
+	}
+	// if a match is being played on this computer, record the frame for
+	// the replay
+	if inMenu == false && inNetworkScreen == false && inTournamentScreen == false &&
+		networkRole == NotNetworked && playingBack == false && replayingPoint == false {
+		recordFrame()
+		recordResultIfOver()
+	}
+	// if we are hosting a network game, tell the other player and the
+	// spectators where everything is
+	if networkRole == Hosting {
+		sendStateToRemotePlayer()
+		sendStateToSpectators()
+		sendStateToWebClients()
+	}
+	// the trail is measured in updates too, so it is the same length
+	// whatever the frame rate is
+	updateBallTrail()
+	// captures take their pictures at the same rate whatever the frame rate is
+	countCaptureUpdate()
+}
+
+// The most updates between two frames. If the game falls further behind than
+// this, because the window was being dragged for example, it doesn't try to
+// catch up.
+const MaxUpdatesPerFrame = UpdatesPerSecond / MinimumFramesPerSecond
+
+// The time that hasn't been used up by updates yet, in thousandths of a
+// second times UpdatesPerSecond, and the time the updates were last worked
+// out. Multiplying by UpdatesPerSecond means we don't need fractions.
+var unusedUpdateTime uint32
+var lastUpdateTicks uint32
+
+// UpdatesDue works out how many times the game must be updated before the next
+// frame is drawn, to keep up with the time that has gone by since the last
+// frame.
+func updatesDue() int {
+	var now uint32
+	now = sdl.GetTicks()
+	unusedUpdateTime = unusedUpdateTime + (now-lastUpdateTicks)*UpdatesPerSecond
+	lastUpdateTicks = now
+	var updates int
+	updates = int(unusedUpdateTime / 1000)
+	unusedUpdateTime = unusedUpdateTime % 1000
+	if updates > MaxUpdatesPerFrame {
+		updates = MaxUpdatesPerFrame
+	}
+	return updates
+}
+
 func cleanup() {
+	// save anything that was being captured
+	stopCapture()
+	// save the match being played, so it can be finished later
+	saveMatchOnQuit()
+	// save the match we were recording, even though it isn't finished
+	if recording == true {
+		saveRecording()
+	}
 	if computersBat != nil {
 		computersBat.Destroy()
 	}
@@ -304,68 +595,123 @@ func getInput() {
 		if isQuitEvent(event) {
 			quit = true
 		}
-		if isKeyDownEvent(event) {
-			if isKeyUp(event) {
-				// If the game is paused we must iognore the up cursor key.
-				// We do not want to move the players bat up if the game is
-				// paused. We can do this by leaving the getInput function
-				// early, by using the return keyword.
-				if paused == true {
-					return
-				}
-				// if the game is in the game over state we must also ignore
-				// the key press
-				if gameOver == true {
-					return
-				}
-				// if the game is not paused we can process the key press
-				myBatY = myBatY - myBatH/4
-				// make sure we do not go off the top of the screen!
-				if myBatY < 0 {
-					myBatY = 0
-				}
-			}
-			if isKeyDown(event) {
-				// If the game is paused we must iognore the down cursor key.
-				// We do not want to move the players bat up if the game is
-				// paused. We can do this by leaving the getInput function
-				// early, by using the return keyword.
-				if paused == true {
-					return
-				}
-				// if the game is in the game over state we must also ignore
-				// the key press
-				if gameOver == true {
-					return
-				}
-				// if the game is not paused we can process the key
-				myBatY = myBatY + myBatH/4
-				// make sure we do not go off the bottom of the screen
-				// we have to account for the heigh of the bat when we do this
-				// becase myBatY is the Y coordinate of the top right of the bat,
-				// but the bottom right (or left) will go of the bottom of the
-				// screen first.
-				if myBatY+myBatH > windowHeight {
-					myBatY = windowHeight - myBatH
-				}
-			}
-			// We must always respond to the paused key being pressed - if the
-			// game is not over.
-			// If the game is running the pause key pauses the game.
-			// But if the game is paused, we must still respond to the paused key.
-			// This is the only way to unpause the game.
-			if isKeyPause(event) {
-				// if the game is in the game over state we must also ignore
-				// the key press
-				if gameOver == true {
-					return
-				}
-				if paused == true {
-					paused = false
-				} else {
-					paused = true
-				}
-			}
+		// the window changing size, F11 for the full screen, F9 to capture
+		// the game and F12 to take a screenshot work whatever is happening
+		if handleWindowEvent(event) == true || handleCaptureEvent(event) == true || handleScreenshotEvent(event) == true {
+			return
+		}
+		// while a replay is playing the keys control the replay
+		if playingBack == true {
+			handlePlaybackEvent(event)
+			return
+		}
+		// any key skips the instant replay
+		if showingInstantReplay == true {
+			handleInstantReplayEvent(event)
+			return
+		}
+		// while the menu is on the screen the keys control the menu
+		if inMenu == true {
+			handleMenuEvent(event)
+			return
+		}
+		if inNetworkScreen == true {
+			handleNetworkScreenEvent(event)
+			return
+		}
+		// the tournament screens, and some keys in a tournament match
+		if handleTournamentEvent(event) == true {
+			return
+		}
+		// return leaves the match summary
+		if handleMatchSummaryEvent(event) == true {
+			return
+		}
+		// the keys chosen with -up-key and -down-key work like the cursor
+		// keys
+		event = useConfiguredKeys(event)
+		// some keys do something different in a network game
+		if networkRole != NotNetworked && handleNetworkGameEvent(event) == true {
+			return
+		}
+		// with rollback the bats are moved by the rollback code
+		if rollbackActive == true {
+			handleRollbackEvent(event)
+			return
+		}
+		handleGameKey(event)
+	}
+}
+
+// HandleGameKey responds to the keys that move the players bat and pause the
+// game. Replays press the keys again by calling it with the keys they recorded.
+func handleGameKey(event sdl.Event) {
+	if isKeyDownEvent(event) == false {
+		return
+	}
+	// some players like to use W and S instead of the cursor keys
+	event = usePlayersControls(event)
+	// remember the key, in case we are recording a replay
+	recordKey(event)
+	if isKeyUp(event) {
+		// If the game is paused we must iognore the up cursor key.
+		// We do not want to move the players bat up if the game is
+		// paused. We can do this by leaving the handleGameKey function
+		// early, by using the return keyword.
+		if paused == true {
+			return
+		}
+		// if the game is in the game over state we must also ignore
+		// the key press
+		if gameOver == true {
+			return
+		}
+		// if the game is not paused we can process the key press
+		myBatY = myBatY - myBatStep()
+		// make sure we do not go off the top of the screen!
+		if myBatY < 0 {
+			myBatY = 0
+		}
+	}
+	if isKeyDown(event) {
+		// If the game is paused we must iognore the down cursor key.
+		// We do not want to move the players bat up if the game is
+		// paused. We can do this by leaving the handleGameKey function
+		// early, by using the return keyword.
+		if paused == true {
+			return
+		}
+		// if the game is in the game over state we must also ignore
+		// the key press
+		if gameOver == true {
+			return
+		}
+		// if the game is not paused we can process the key
+		myBatY = myBatY + myBatStep()
+		// make sure we do not go off the bottom of the screen
+		// we have to account for the heigh of the bat when we do this
+		// becase myBatY is the Y coordinate of the top right of the bat,
+		// but the bottom right (or left) will go of the bottom of the
+		// screen first.
+		if myBatY+myBatH > windowHeight {
+			myBatY = windowHeight - myBatH
+		}
+	}
+	// We must always respond to the paused key being pressed - if the
+	// game is not over.
+	// If the game is running the pause key pauses the game.
+	// But if the game is paused, we must still respond to the paused key.
+	// This is the only way to unpause the game.
+	if isKeyPause(event) {
+		// if the game is in the game over state we must also ignore
+		// the key press
+		if gameOver == true {
+			return
+		}
+		if paused == true {
+			paused = false
+		} else {
+			paused = true
 		}
 	}
 }
@@ -419,24 +765,42 @@ func updateState() {
 	if gameOver == true {
 		return
 	}
+	countMatchFrame()
 	// update the balls state
 	updateBallState()
-	// move the computer players bat
-	updateComputersBatPosition()
+	// move the computer players bat, unless another player is moving it
+	// over the network or two people are playing on this computer
+	if twoPlayers == true {
+		moveBatsForTwoPlayers()
+	} else if networkRole == NotNetworked {
+		updateComputersBatPosition()
+	}
+	// remember where everything is for the instant replay
+	recordRallyFrame()
 	// now check for collisions between the ball/walls and the ball/bats
 	checkForCollisions()
+	// in a timed match the golden point wins, and the match ends when the
+	// time runs out
+	checkForGoldenPoint()
+	updateClock()
 }
 
 func updateBallState() {
 	// just update the position.....
 	var frameTime = float64(1) / float64(60)
+	// gravity and wind change the balls direction a little bit every frame
+	ballDirX, ballDirY = applyFieldForces(ballDirX, ballDirY, fieldUpdates)
+	// and in a zone the ball moves faster or slower than normal
+	var speed = zoneSpeed(ballX, ballY)
 	// work out how far the ball moved during the last "frame"
 	// Easy - just the direction times the frameTime
-	var xDelta = ballDirX * frameTime
-	var yDelta = ballDirY * frameTime
+	var xDelta = ballDirX * frameTime * speed
+	var yDelta = ballDirY * frameTime * speed
 	// the balls new position is the last position + the delta for this frame
 	ballX = ballX + xDelta
 	ballY = ballY + yDelta
+	noteBallSpeed(xDelta, yDelta)
+	fieldUpdates = fieldUpdates + 1
 }
 
 // This is the games artifical intelligence.
@@ -444,17 +808,23 @@ func updateBallState() {
 //    Chase the ball when it is going towards the AI player.
 //    Only chase the ball when it is on its side of the playing field.
 //    Move towards the center when the ball is moving away from the AI player.
+// When there are field modifiers the ball does not fly in a straight line,
+// so the AI chases the place where it predicts the ball will reach its bat.
 func updateComputersBatPosition() {
 	// work out the frame time
 	var frameTime = float64(1) / float64(60)
-	// calculate how far the computers bat could have moved in the frame time
+	// calculate how far the computers bat could have moved in the frame time.
+	// The harder the computer is, the faster it moves.
 	var deltaY float64
-	deltaY = ComputersBatSpeed * frameTime
+	deltaY = computersBatPixelsPerSecond() * computersDifficultySpeed() * frameTime
 
 	var middleOfBatY float64
 	middleOfBatY = float64(computersBatY) + float64(computersBatH/2)
 	var middleOfBallY float64
 	middleOfBallY = ballY + float64(ballH/2)
+	if fieldModifiersActive() {
+		middleOfBallY = predictBallY()
+	}
 	var middleOfTheScreenY float64
 	middleOfTheScreenY = float64(windowHeight / 2)
 	// if the ball is on the computers half of the screen and the ball is moving
@@ -499,6 +869,8 @@ func checkForCollisions() {
 	if hitPlayersBat == true {
 		// the ball hit the players bat, so reflect it along a new direction
 		reflectBallFromPlayersBat()
+		lastHitBy = Player
+		sparksAt(ballX, ballY+float64(ballH)/2)
 	}
 	// check to see if the ball hit the computers bat
 	var computersBatHit bool
@@ -506,6 +878,12 @@ func checkForCollisions() {
 	if computersBatHit == true {
 		// the ball hit the players bat, so reflect it along a new direction
 		reflectBallFromComputersBat()
+		lastHitBy = Computer
+		sparksAt(ballX+float64(ballW), ballY+float64(ballH)/2)
+	}
+	// in breakout mode the ball can also hit the bricks
+	if gameMode == BreakoutMode {
+		checkForBallBrickCollisions()
 	}
 }
 
@@ -519,29 +897,38 @@ func checkForBallWallCollisions() {
 		ballY = 0.0
 		// yes we hit the top, so reflect the ball back by changing
 		ballDirY = ballDirY * -1
+		sparksAt(ballX+float64(ballW)/2, 0)
 	} else if ballY > playingFieldBottom {
 		// we hit the bottom so stop the ball from going off the bottom of the
 		// screen
 		ballY = playingFieldBottom
 		// now reflect the ball back
 		ballDirY = ballDirY * -1
+		sparksAt(ballX+float64(ballW)/2, float64(windowHeight))
 
 	}
 	//check for left wall next
 	if ballX < 0 { // the left edge of the x coordinate of the screen
 		// the ball hit the left wall, so the computer scored a point
 		computersScore = computersScore + 1
+		// show how the point was won again, before the ball moves
+		startInstantReplay()
+		notePoint(Computer)
+		goalBurstAt(0, ballY+float64(ballH)/2, Computer)
 		// now we need to reset the game state so that the ball starts
 		// in the middle again.
 		resetGameState()
-		if computersScore == WinningScore {
+		if computersScore == computersTargetScore {
 			gameOver = true
 		}
 	} else if ballX > playingFieldRight {
 		// we hit the right wall so the player scored a point
 		myScore = myScore + 1
+		startInstantReplay()
+		notePoint(Player)
+		goalBurstAt(float64(windowWidth), ballY+float64(ballH)/2, Player)
 		resetGameState()
-		if myScore == WinningScore {
+		if myScore == myTargetScore {
 			gameOver = true
 		}
 	}
@@ -555,6 +942,10 @@ func resetGameState() {
 	initialiseBallPosition()
 	// Now we need to set the balls direction
 	initialiseBallDirection()
+	// nobody has hit the new ball yet
+	lastHitBy = NoOne
+	// remember who served, for the statistics
+	noteServe()
 }
 func checkForBallPayersBatCollisions() bool {
 	// Did the ball collide with the players bat?
@@ -634,6 +1025,8 @@ func reflectBallFromPlayersBat() {
 	// now we know the vector we want to change the balls direction to, we can set it.
 	// The 1 just means the vector always goes to the right
 	setBallDirection(1, scaledReflection)
+	// count the hit, and whether it was near the edge of the bat
+	countBatHit(Player, scaledReflection)
 }
 
 func checkForBallComputersBatCollisions() bool {
@@ -707,12 +1100,13 @@ func reflectBallFromComputersBat() {
 	// now we know the vector we want to change the balls direction to, we can set it.
 	// The -1 just means the vector always goes to the left
 	setBallDirection(-1, scaledReflection)
+	countBatHit(Computer, scaledReflection)
 }
 
 // Render updates the screen, based on the new positions of the bats and the ball.
 func render() {
 	var fps uint32
-	fps = 60
+	fps = uint32(framesPerSecond)
 	var delay uint32
 	delay = 1000 / fps
 
@@ -720,19 +1114,85 @@ func render() {
 	frameStart = sdl.GetTicks()
 
 	renderer.Clear()
-	renderMyBat()
-	renderComputersBat()
+	// if the menu is on the screen we draw the menu instead of the game
+	if inMenu == true {
+		renderMenu()
+		showFrame(frameStart, delay)
+		return
+	}
+	if inNetworkScreen == true {
+		renderNetworkScreen()
+		showFrame(frameStart, delay)
+		return
+	}
+	if inTournamentScreen == true {
+		renderTournamentScreen()
+		showFrame(frameStart, delay)
+		return
+	}
+	renderGame()
+	// capture the game before anything that isn't part of it is drawn
+	captureFrame()
+	if playingBack == true {
+		renderPlaybackStatus()
+	}
+	// Show the game window window.
+	showFrame(frameStart, delay)
+}
+
+// RenderGame draws the playing field, the bats, the ball and the scores.
+func renderGame() {
+	// when the match is over the statistics are shown instead
+	if showingMatchSummary() == true {
+		renderMatchSummary()
+		renderParticles()
+		return
+	}
+	renderBackground()
+	renderFieldModifiers()
+	// the instant replay draws the bats where they were
+	if showingInstantReplay == false {
+		renderMyBat()
+		renderComputersBat()
+	}
+	if gameMode == BreakoutMode {
+		renderBricks()
+	}
 	renderScore()
+	renderClock()
 	// if the game is over render the gameOver graphic
-	if gameOver == true {
+	if showingInstantReplay == true {
+		renderInstantReplay()
+	} else if gameOver == true {
 		renderGameOver()
 	} else {
-		// otherwise we need to draw the ball
+		// otherwise we need to draw the ball, with its trail behind it
+		renderBallTrail()
 		renderBall()
 	}
-	// Show the game window window.
-	renderer.Present()
+	// the sparks and confetti go on top of everything in the game
+	renderParticles()
+	renderNetworkStatus()
+	renderSpectatorCount()
+	if playingTournamentMatch == true {
+		renderTournamentNames()
+	}
+}
+
+// ShowFrame shows what has been drawn in the window, then waits until it is
+// time to draw the next frame. A screenshot is taken just before the frame is
+// shown, and the capture messages are drawn on top afterwards so they are not
+// in the screenshot.
+func showFrame(frameStart, delay uint32) {
+	takeScreenshotIfWanted()
+	renderCaptureStatus()
+	presentPlayfield()
+	waitForNextFrame(frameStart, delay)
+}
 
+// WaitForNextFrame waits until it is time to draw the next frame, so that the
+// game runs at the same speed on fast and slow computers.
+func waitForNextFrame(frameStart, delay uint32) {
 	var frameTime uint32
 	frameTime = sdl.GetTicks() - frameStart
 	if frameTime < delay {
@@ -741,6 +1201,11 @@ func render() {
 }
 
 func loadGraphics() {
+	// vector graphics don't use any pictures
+	if vectorGraphics == true {
+		setVectorSizes()
+		return
+	}
 	loadMyBatGraphic()
 	setSizeOfMyBat()
 	loadComputersBatGraphic()
@@ -754,15 +1219,15 @@ func loadGraphics() {
 }
 
 func loadMyBatGraphic() {
-	myBat = loadGraphic("./assets/graphics/bat.png")
+	myBat = loadGraphic("graphics/bat.png")
 }
 
 func loadComputersBatGraphic() {
-	computersBat = loadGraphic("./assets/graphics/bat.png")
+	computersBat = loadGraphic("graphics/bat.png")
 }
 
 func loadBallGraphic() {
-	ball = loadGraphic("./assets/graphics/ball.png")
+	ball = loadGraphic("graphics/ball.png")
 }
 
 func loadScores() {
@@ -771,12 +1236,12 @@ func loadScores() {
 	for i = 0; i < 12; i++ {
 		// create the score name dynamiclly
 		// The filenames look like this:
-		// ./assets/graphics/1.png or ./assets/graphics/10.png
+		// graphics/1.png or graphics/10.png
 		// So we can use the value of the loop counter - i- to help generate the
 		// file name in the loop.
 		// The strconv.Itoa function converts a decimal integer number to a string
 		// the plus - +'s - join the strings together
-		scoreGfxFilename = "./assets/graphics/" + strconv.Itoa(i) + ".png"
+		scoreGfxFilename = "graphics/" + strconv.Itoa(i) + ".png"
 		// load the graphic and store it the i'th position in the scores array
 		// So the first score is at scoresGfx[0] because i started at zero. The next
 		// graphic is at scoresGfx[1] because the next value of i is one.
@@ -785,13 +1250,33 @@ func loadScores() {
 }
 
 func loadGameOverGraphic() {
-	gameOverGfx = loadGraphic("./assets/graphics/GameOver.png")
+	gameOverGfx = loadGraphic("graphics/GameOver.png")
 }
 
+// LoadGraphic loads one of the game's pictures, for example
+// "graphics/ball.png", or crashes trying. The picture might be built into
+// the game instead of being in a file (see assets.go), so it is read into
+// memory first and SDL loads it from there.
 func loadGraphic(filename string) *sdl.Texture {
+	var data []byte
 	var err error
-
-	image, err = img.Load(filename)
+	data, err = readAsset(filename)
+	if err == nil && len(data) == 0 {
+		err = errors.New(filename + " is empty")
+	}
+	if err != nil {
+		fmt.Print("Failed to load PNG: ")
+		fmt.Println(err)
+		panic(err)
+	}
+	// RWFromMem lets SDL read the picture from memory as if it were a
+	// file. The 1 tells Load_RW to close it afterwards.
+	var picture *sdl.RWops
+	picture = sdl.RWFromMem(unsafe.Pointer(&data[0]), len(data))
+	image, err = img.Load_RW(picture, 1)
+	// SDL has been reading data through a pointer the garbage collector
+	// can't see, so data must not be thrown away until SDL has finished
+	runtime.KeepAlive(data)
 	if err != nil {
 		fmt.Print("Failed to load PNG: ")
 		fmt.Println(err)
@@ -815,7 +1300,7 @@ func initialiseMyBatPosition() {
 
 func initialiseComputersBatPosition() {
 	computersBatX = windowWidth - (windowWidth / 10) - computersBatW/2
-	computersBatY = windowHeight/2 - myBatH/2
+	computersBatY = windowHeight/2 - computersBatH/2
 }
 
 func initialiseBallPosition() {
@@ -846,6 +1331,10 @@ func setSizeOfMyBat() {
 	}
 	myBatW = int(w)
 	myBatH = int(h)
+	// a handicap can make the bat taller or shorter than the graphic
+	if myBatHeight > 0 {
+		myBatH = myBatHeight
+	}
 }
 
 func setSizeOfComputersBat() {
@@ -859,6 +1348,10 @@ func setSizeOfComputersBat() {
 	}
 	computersBatW = int(w)
 	computersBatH = int(h)
+	// a handicap can make the bat taller or shorter than the graphic
+	if computersBatHeight > 0 {
+		computersBatH = computersBatHeight
+	}
 }
 
 func setSizeOfBall() {
@@ -902,55 +1395,43 @@ func setSizeOfGameOverGraphic() {
 
 func renderMyBat() {
 
-	var src, dst sdl.Rect
-
-	src.X = 0
-	src.Y = 0
-	src.W = int32(myBatW)
-	src.H = int32(myBatH)
+	var dst sdl.Rect
 
+	// the theme's bat is stretched or squashed to the size of the bat
 	dst.X = int32(myBatX)
 	dst.Y = int32(myBatY)
 	dst.W = int32(myBatW)
 	dst.H = int32(myBatH)
 
-	renderer.Copy(myBat, &src, &dst)
+	// draw the bat the way the theme says, in the colour the player chose
+	renderThemePicture(&currentTheme().LeftBat, dst, myBatColour())
 
 }
 
 func renderComputersBat() {
 
-	var src, dst sdl.Rect
-
-	src.X = 0
-	src.Y = 0
-	src.W = int32(computersBatW)
-	src.H = int32(computersBatH)
+	var dst sdl.Rect
 
+	// the theme's bat is stretched or squashed to the size of the bat
 	dst.X = int32(computersBatX)
 	dst.Y = int32(computersBatY)
 	dst.W = int32(computersBatW)
 	dst.H = int32(computersBatH)
 
-	renderer.Copy(computersBat, &src, &dst)
+	renderThemePicture(&currentTheme().RightBat, dst, untinted)
 
 }
 
 func renderBall() {
 
-	var src, dst sdl.Rect
-
-	src.X = 0
-	src.Y = 0
-	src.W = int32(ballW)
-	src.H = int32(ballH)
+	var dst sdl.Rect
 
 	dst.X = int32(ballX)
 	dst.Y = int32(ballY)
 	dst.W = int32(ballW)
 	dst.H = int32(ballH)
 
-	renderer.Copy(ball, &src, &dst)
+	renderThemePicture(&currentTheme().Ball, dst, untinted)
 
 }
 
@@ -960,51 +1441,36 @@ func renderScore() {
 }
 
 func renderMyScore() {
-	var src, dst sdl.Rect
-
-	src.X = 0
-	src.Y = 0
-	src.W = int32(scoreW)
-	src.H = int32(scoreH)
+	var dst sdl.Rect
 
 	dst.X = int32(myScoreX)
 	dst.Y = int32(myScoreY)
 	dst.W = int32(scoreW)
 	dst.H = int32(scoreH)
 
-	renderer.Copy(scoresGfx[myScore], &src, &dst)
+	renderThemeDigit(&currentTheme().Digits, dst, myScore)
 }
 
 func renderComputersScore() {
-	var src, dst sdl.Rect
-
-	src.X = 0
-	src.Y = 0
-	src.W = int32(scoreW)
-	src.H = int32(scoreH)
+	var dst sdl.Rect
 
 	dst.X = int32(computersScoreX)
 	dst.Y = int32(computersScoreY)
 	dst.W = int32(scoreW)
 	dst.H = int32(scoreH)
 
-	renderer.Copy(scoresGfx[computersScore], &src, &dst)
+	renderThemeDigit(&currentTheme().Digits, dst, computersScore)
 }
 
 func renderGameOver() {
-	var src, dst sdl.Rect
-
-	src.X = 0
-	src.Y = 0
-	src.W = int32(gameOverW)
-	src.H = int32(gameOverH)
+	var dst sdl.Rect
 
 	dst.X = int32(gameOverX)
 	dst.Y = int32(gameOverY)
 	dst.W = int32(gameOverW)
 	dst.H = int32(gameOverH)
 
-	renderer.Copy(gameOverGfx, &src, &dst)
+	renderThemePicture(&currentTheme().GameOver, dst, untinted)
 }
 
 // CheckQuit checks if the user has clicked the window's close button.
@@ -1029,7 +1495,7 @@ func createWindow(w, h int) *sdl.Window {
 	var err error
 
 	window, err = sdl.CreateWindow("Pong Game", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		w, h, sdl.WINDOW_SHOWN)
+		w, h, windowFlags())
 	if err != nil {
 		panic(err)
 	}
@@ -1040,7 +1506,8 @@ func createWindow(w, h int) *sdl.Window {
 func createRenderer(w *sdl.Window) *sdl.Renderer {
 	var r *sdl.Renderer
 	var err error
-	r, err = sdl.CreateRenderer(w, -1, sdl.RENDERER_ACCELERATED)
+	// the renderer must be able to draw into a texture for the playing field
+	r, err = sdl.CreateRenderer(w, -1, sdl.RENDERER_ACCELERATED|sdl.RENDERER_TARGETTEXTURE)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ---- Things the tests share ----
+//
+// The game keeps everything in package variables, so every test starts by
+// setting them up itself. The tests never open a window.
+//
+// Every file the game reads might have been damaged, changed by hand or saved
+// by a different version of the game. The tests for the loaders start from a
+// file that loads, change one thing about it, and check the loader turns it
+// away instead of letting it crash the game later.
+
+// SetUpTestMatch sets up a match the way the game would with no command line
+// flags, but without a window. The player's files are kept in a folder that
+// is thrown away when the test finishes.
+func setUpTestMatch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	assetsDirectory = ""
+	gameMode = ClassicMode
+	arenaNumber = 0
+	zones = arenas[arenaNumber].zones
+	gravity = 0
+	wind = 0
+	timeLimit = 0
+	difficulty = NormalDifficulty
+	ballSpeed = 550
+	computerSpeed = 350
+	winningScore = HighestScore
+	myBatHeight = 0
+	computersBatHeight = 0
+	myBatSpeed = 100
+	computersBatSpeed = 100
+	myStartingScore = 0
+	computersStartingScore = 0
+	myTargetScore = winningScore
+	computersTargetScore = winningScore
+	netcode = NetcodeHost
+	replayDirectory = ""
+	twoPlayers = false
+	// the server doesn't need a window either
+	initialiseServer()
+	networkRole = NotNetworked
+	startNewGame()
+	inMenu = false
+}
+
+// WriteTestFile writes a file the test needs, and stops the test if it
+// can't.
+func writeTestFile(t *testing.T, filename string, data []byte) {
+	var err error
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err == nil {
+		err = os.WriteFile(filename, data, 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WriteTestJSON saves v as a JSON file.
+func writeTestJSON(t *testing.T, filename string, v interface{}) {
+	var data []byte
+	var err error
+	data, err = json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filename, data)
+}
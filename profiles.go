@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Player profiles ----
+//
+// A profile remembers a player between games: their name, which keys they
+// like to use and the colour of their bat. The player chooses their profile
+// on the menu, and the results of their matches are saved under their name
+// (see results.go).
+//
+// The profiles are saved in a JSON file in the player's settings folder.
+
+// The version of the profiles file. If the file changes, this must change
+// too, so an old file is not read wrongly.
+const ProfilesFileVersion = 1
+
+// The name of the file the profiles are saved in.
+const ProfilesFilename = "profiles.json"
+
+// The keys a player can use to move their bat.
+const ArrowControls = "ARROWS"
+const WSControls = "W AND S"
+
+// The most profiles we keep.
+const MaximumProfiles = 20
+
+// A batColour is one of the colours a player's bat can be.
+type batColour struct {
+	name    string
+	r, g, b uint8
+}
+
+// The colours a player can choose. The bat graphic is white, so each colour
+// is made by tinting the graphic.
+var batColours = [...]batColour{
+	{"WHITE", 255, 255, 255},
+	{"RED", 255, 64, 64},
+	{"GREEN", 64, 255, 64},
+	{"BLUE", 64, 160, 255},
+	{"YELLOW", 255, 255, 0},
+	{"ORANGE", 255, 160, 0},
+	{"PURPLE", 200, 96, 255},
+}
+
+// A profile is one player's settings. It is saved as JSON, so the names start
+// with capital letters.
+type profile struct {
+	Name     string
+	Controls string
+	Colour   string
+}
+
+// The profiles file.
+type profilesFile struct {
+	Version  int
+	Profiles []profile
+	// the profile chosen last time, so it is chosen again
+	Current int
+}
+
+// Everybody's profiles, and the number of the one being used.
+var profiles []profile
+var currentProfile int
+
+// The inProfileScreen flag is true while the profile screen is showing. The
+// profile screen is part of the menu, so inMenu is true as well.
+var inProfileScreen bool
+
+// The line on the profile screen the player has selected.
+var profileSelection int
+
+// A message to show at the bottom of the profile screen.
+var profileMessage string
+
+// The lines on the profile screen.
+const ProfileNameLine = 0
+const ProfileControlsLine = 1
+const ProfileColourLine = 2
+const ProfileNewLine = 3
+const ProfileDeleteLine = 4
+const NumberOfProfileLines = 5
+
+// LoadProfiles loads the profiles saved last time. If there aren't any, or
+// they can't be read, there is one new profile.
+func loadProfiles() {
+	var f profilesFile
+	var err error
+	f, err = readProfiles()
+	if err != nil || len(f.Profiles) == 0 {
+		profiles = []profile{newProfile("PLAYER 1")}
+		currentProfile = 0
+		return
+	}
+	profiles = f.Profiles
+	currentProfile = f.Current
+	if currentProfile < 0 || currentProfile >= len(profiles) {
+		currentProfile = 0
+	}
+}
+
+// ReadProfiles reads the profiles file, and checks it makes sense.
+func readProfiles() (profilesFile, error) {
+	var f profilesFile
+	var data []byte
+	var err error
+	data, err = os.ReadFile(userFilePath(ProfilesFilename))
+	if err != nil {
+		return f, err
+	}
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return f, err
+	}
+	if f.Version != ProfilesFileVersion {
+		return f, errors.New("the profiles were saved by a different version of pong")
+	}
+	if len(f.Profiles) > MaximumProfiles {
+		return f, errors.New("there are too many profiles")
+	}
+	var p profile
+	for _, p = range f.Profiles {
+		if p.Name == "" || len(p.Name) > MaximumNameLength {
+			return f, errors.New("a profile has a bad name")
+		}
+		if p.Controls != ArrowControls && p.Controls != WSControls {
+			return f, errors.New("a profile has unknown controls")
+		}
+		if findBatColour(p.Colour) < 0 {
+			return f, errors.New("a profile has an unknown colour")
+		}
+	}
+	return f, nil
+}
+
+// SaveProfiles saves everybody's profiles.
+func saveProfiles() {
+	var f profilesFile
+	f.Version = ProfilesFileVersion
+	f.Profiles = profiles
+	f.Current = currentProfile
+	var data []byte
+	var err error
+	data, err = json.MarshalIndent(f, "", "  ")
+	if err == nil {
+		err = os.WriteFile(userFilePath(ProfilesFilename), data, 0644)
+	}
+	if err != nil {
+		profileMessage = "COULD NOT SAVE THE PROFILES"
+	}
+}
+
+// NewProfile makes a profile with the normal settings.
+func newProfile(name string) profile {
+	var p profile
+	p.Name = name
+	p.Controls = ArrowControls
+	p.Colour = batColours[0].name
+	return p
+}
+
+// PlayersProfile is the profile of the player using this computer. If the
+// profiles haven't been loaded, which happens in the dedicated server, it is
+// a new profile.
+func playersProfile() profile {
+	if len(profiles) == 0 {
+		return newProfile("PLAYER 1")
+	}
+	return profiles[currentProfile]
+}
+
+// FindBatColour finds the number of a colour in batColours from its name.
+// It returns -1 if there isn't a colour with that name.
+func findBatColour(name string) int {
+	var i int
+	for i = 0; i < len(batColours); i++ {
+		if batColours[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// MyBatColour is the colour to draw my bat. It is the colour from my
+// profile, unless the left bat belongs to somebody else, like in a
+// tournament.
+func myBatColour() batColour {
+	if playingTournamentMatch == true || networkRole == Joined || networkRole == Spectating {
+		return batColours[0]
+	}
+	var i int
+	i = findBatColour(playersProfile().Colour)
+	if i < 0 {
+		return batColours[0]
+	}
+	return batColours[i]
+}
+
+// UsePlayersControls changes the W and S keys into the up and down cursor
+// keys for a player who likes to use W and S. When two people are playing on
+// one computer the left player always uses W and S anyway, so nothing is
+// changed.
+func usePlayersControls(event sdl.Event) sdl.Event {
+	if twoPlayers == true || playersProfile().Controls != WSControls {
+		return event
+	}
+	if isKeyDownEvent(event) == false {
+		return event
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	var changed sdl.KeyDownEvent
+	changed = *keyDownEvt
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_w:
+		changed.Keysym.Sym = sdl.K_UP
+		return &changed
+	case sdl.K_s:
+		changed.Keysym.Sym = sdl.K_DOWN
+		return &changed
+	}
+	return event
+}
+
+// ---- The profile screen ----
+
+// OpenProfileScreen shows the profile screen.
+func openProfileScreen() {
+	inProfileScreen = true
+	profileSelection = ProfileNameLine
+	profileMessage = ""
+}
+
+// CloseProfileScreen checks the profile, saves it, and goes back to the menu.
+func closeProfileScreen() {
+	if checkProfileName() == false {
+		return
+	}
+	saveProfiles()
+	inProfileScreen = false
+}
+
+// CheckProfileName makes sure the profile has a name, and that nobody else
+// has the same one, because the results are saved under the name.
+func checkProfileName() bool {
+	var name string
+	name = profiles[currentProfile].Name
+	if name == "" {
+		profileMessage = "THE PLAYER NEEDS A NAME"
+		return false
+	}
+	var i int
+	for i = 0; i < len(profiles); i++ {
+		if i != currentProfile && profiles[i].Name == name {
+			profileMessage = "SOMEBODY ELSE IS CALLED " + name
+			return false
+		}
+	}
+	return true
+}
+
+// ChangeProfile chooses the next profile, or the one before if step is -1.
+func changeProfile(step int) {
+	currentProfile = (currentProfile + step + len(profiles)) % len(profiles)
+}
+
+// HandleProfileEvent responds to the keys pressed while the profile screen is
+// showing.
+func handleProfileEvent(event sdl.Event) {
+	if isKeyDownEvent(event) == false {
+		return
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	var p *profile
+	p = &profiles[currentProfile]
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_UP:
+		if profileSelection > 0 {
+			profileSelection = profileSelection - 1
+		}
+	case sdl.K_DOWN:
+		if profileSelection < NumberOfProfileLines-1 {
+			profileSelection = profileSelection + 1
+		}
+	case sdl.K_LEFT, sdl.K_RIGHT:
+		var step int
+		step = 1
+		if keyDownEvt.Keysym.Sym == sdl.K_LEFT {
+			step = -1
+		}
+		switch profileSelection {
+		case ProfileNameLine:
+			// choose somebody else's profile
+			if checkProfileName() == true {
+				changeProfile(step)
+				profileMessage = ""
+			}
+		case ProfileControlsLine:
+			if p.Controls == ArrowControls {
+				p.Controls = WSControls
+			} else {
+				p.Controls = ArrowControls
+			}
+		case ProfileColourLine:
+			var i int
+			i = findBatColour(p.Colour)
+			i = (i + step + len(batColours)) % len(batColours)
+			p.Colour = batColours[i].name
+		}
+	case sdl.K_RETURN:
+		switch profileSelection {
+		case ProfileNewLine:
+			addProfile()
+		case ProfileDeleteLine:
+			deleteProfile()
+		default:
+			closeProfileScreen()
+		}
+	case sdl.K_BACKSPACE:
+		if profileSelection == ProfileNameLine && len(p.Name) > 0 {
+			p.Name = p.Name[:len(p.Name)-1]
+		}
+	case sdl.K_ESCAPE:
+		closeProfileScreen()
+	default:
+		if profileSelection != ProfileNameLine {
+			return
+		}
+		var letter rune
+		var ok bool
+		letter, ok = keyToLetter(keyDownEvt.Keysym.Sym)
+		if ok == true && len(p.Name) < MaximumNameLength {
+			p.Name = p.Name + string(letter)
+		}
+	}
+}
+
+// AddProfile makes a new profile and chooses it, ready for its name to be
+// typed.
+func addProfile() {
+	if checkProfileName() == false {
+		return
+	}
+	if len(profiles) >= MaximumProfiles {
+		profileMessage = "THERE ARE TOO MANY PLAYERS"
+		return
+	}
+	profiles = append(profiles, newProfile("PLAYER "+strconv.Itoa(len(profiles)+1)))
+	currentProfile = len(profiles) - 1
+	profileSelection = ProfileNameLine
+	profileMessage = "TYPE A NAME FOR THE NEW PLAYER"
+}
+
+// DeleteProfile deletes the chosen profile. Their results are kept. There must
+// always be at least one profile.
+func deleteProfile() {
+	if len(profiles) == 1 {
+		profileMessage = "THERE MUST BE AT LEAST ONE PLAYER"
+		return
+	}
+	profiles = append(profiles[:currentProfile], profiles[currentProfile+1:]...)
+	if currentProfile >= len(profiles) {
+		currentProfile = len(profiles) - 1
+	}
+	profileSelection = ProfileNameLine
+	profileMessage = ""
+}
+
+// RenderProfileScreen draws the profile screen, with a bat in the player's
+// colour.
+func renderProfileScreen() {
+	renderTextCentred("PLAYER", windowWidth/2, 48, 8, 255, 255, 255)
+	var p profile
+	p = profiles[currentProfile]
+	var y int
+	y = windowHeight/2 - 100
+	renderProfileLine(ProfileNameLine, "< "+p.Name+" >", y)
+	renderProfileLine(ProfileControlsLine, "CONTROLS: "+p.Controls, y+50)
+	renderProfileLine(ProfileColourLine, "COLOUR: "+p.Colour, y+100)
+	renderProfileLine(ProfileNewLine, "NEW PLAYER", y+170)
+	renderProfileLine(ProfileDeleteLine, "DELETE PLAYER", y+220)
+	// show the bat in the chosen colour
+	var colour batColour
+	colour = batColours[findBatColour(p.Colour)]
+	var bat sdl.Rect
+	bat.X = int32(windowWidth/2 - 300)
+	bat.Y = int32(y)
+	bat.W = int32(myBatW)
+	bat.H = int32(myBatH)
+	renderThemePicture(&currentTheme().LeftBat, bat, colour)
+	if profileMessage != "" {
+		renderTextCentred(profileMessage, windowWidth/2, windowHeight-90, 3, 255, 128, 0)
+	}
+	renderTextCentred("LEFT/RIGHT TO CHOOSE OR CHANGE, TYPE TO CHANGE THE NAME, ESCAPE FOR THE MENU",
+		windowWidth/2, windowHeight-40, 2, 128, 128, 128)
+}
+
+// RenderProfileLine draws one line of the profile screen. The selected line
+// is drawn in yellow.
+func renderProfileLine(line int, text string, y int) {
+	if line == profileSelection {
+		renderTextCentred(text, windowWidth/2, y, 4, 255, 255, 0)
+	} else {
+		renderTextCentred(text, windowWidth/2, y, 4, 255, 255, 255)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadProfiles(t *testing.T) {
+	var tooMany []profile
+	var i int
+	for i = 0; i <= MaximumProfiles; i++ {
+		tooMany = append(tooMany, newProfile("PLAYER"))
+	}
+	var tests = []struct {
+		name   string
+		change func(f *profilesFile)
+		ok     bool
+	}{
+		{"good profiles", func(f *profilesFile) {}, true},
+		{"a different version", func(f *profilesFile) { f.Version = ProfilesFileVersion + 1 }, false},
+		{"too many profiles", func(f *profilesFile) { f.Profiles = tooMany }, false},
+		{"no name", func(f *profilesFile) { f.Profiles[0].Name = "" }, false},
+		{"a long name", func(f *profilesFile) { f.Profiles[0].Name = strings.Repeat("A", MaximumNameLength+1) }, false},
+		{"unknown controls", func(f *profilesFile) { f.Profiles[1].Controls = "MOUSE" }, false},
+		{"an unknown colour", func(f *profilesFile) { f.Profiles[1].Colour = "TARTAN" }, false},
+	}
+	var test struct {
+		name   string
+		change func(f *profilesFile)
+		ok     bool
+	}
+	for _, test = range tests {
+		setUpTestMatch(t)
+		var f profilesFile
+		f.Version = ProfilesFileVersion
+		f.Profiles = []profile{newProfile("PLAYER 1"), newProfile("PLAYER 2")}
+		f.Profiles[1].Controls = WSControls
+		test.change(&f)
+		writeTestJSON(t, userFilePath(ProfilesFilename), f)
+		var err error
+		_, err = readProfiles()
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: loaded profiles it should have turned away", test.name)
+		}
+	}
+}
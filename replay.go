@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Replays ----
+//
+// The game is deterministic. If we start from the same game state and the
+// players press the same keys on the same frames, the match always plays out
+// in exactly the same way. So to record a match we don't need to save where
+// the ball was on every frame. We only need the settings, the game state at
+// the start, and which keys were pressed on each frame. That makes a replay
+// file very small.
+//
+// To play a replay back we load the settings and the starting game state,
+// and then press the recorded keys again, one frame at a time.
+//
+//	pong -record replays
+//	pong replay replays/pong-2015-06-01-153000.replay
+
+// The version of the replay files. If the game changes in a way that would
+// make an old replay play out differently, this must change too. Otherwise
+// the old replay would go wrong halfway through.
+const ReplayFileVersion = 5
+
+// Every replay file starts with this, followed by the version and a new line.
+const ReplayFilePrefix = "PONG REPLAY "
+
+// How often a playback saves the game state, in frames. Going backwards in a
+// replay means starting again from one of these saved game states.
+const KeyframeInterval = 10 * UpdatesPerSecond
+
+// How far the cursor keys move through a replay, in frames.
+const SeekFrames = 5 * UpdatesPerSecond
+
+// A replay holds everything needed to play a match again. Each input is one
+// frame. The lowest 2 bits are the key pressed on that frame (InputUp,
+// InputDown or InputPause), the next 2 bits are the keys the left player was
+// holding and the 2 bits after that are the keys the right player was
+// holding, when two people played on one computer.
+type replay struct {
+	Version       int
+	Settings      netSettings
+	TwoPlayers    bool
+	WindowWidth   int
+	WindowHeight  int
+	MyBatW        int
+	ComputersBatW int
+	BallW         int
+	BallH         int
+	Inputs        []byte
+}
+
+// The folder to save replays in. If it is empty, matches are not recorded.
+var replayDirectory string
+
+// The recording flag is true while a match is being recorded.
+var recording bool
+
+// The replay being recorded.
+var recordedReplay replay
+
+// The key pressed and the keys held down on this frame, for the recording.
+var keyThisFrame int
+var keysHeldThisFrame int
+
+// The replay file to play, from the command line.
+var replayFilename string
+
+// The playingBack flag is true while a replay is being played.
+var playingBack bool
+
+// The replay being played.
+var playback replay
+
+// How many frames of the replay have been played.
+var playbackFrame int
+
+// Where the playback has got to. Slow motion moves this on by less than one
+// frame each time, so it is a float64.
+var playbackPosition float64
+
+// The speeds a replay can be played at, and which one is chosen.
+var playbackSpeeds = [...]float64{0.25, 0.5, 1, 2, 4, 8}
+var playbackSpeed int
+
+// The playbackPaused flag is true while the replay is paused.
+var playbackPaused bool
+
+// The game state every KeyframeInterval frames.
+var keyframes []gameState
+
+// The keys the players were holding down on the frame being played.
+var playbackLeftHeld int
+var playbackRightHeld int
+
+// AddReplayFlags adds the command line flags for recording replays.
+func addReplayFlags() {
+	flag.StringVar(&replayDirectory, "record", "", "record every match to a replay file in this folder")
+}
+
+// ---- Recording ----
+
+// StartRecording starts recording a new match. It is called whenever a new
+// match starts.
+func startRecording() {
+	if replayDirectory == "" || playingBack == true {
+		return
+	}
+	// a match that was left before it finished is saved too
+	if recording == true {
+		saveRecording()
+	}
+	recording = true
+	recordedReplay = replay{}
+	recordedReplay.Version = ReplayFileVersion
+	recordedReplay.Settings = *makeNetSettings()
+	var start gameState
+	start = saveGameState()
+	recordedReplay.Settings.StartState = &start
+	recordedReplay.TwoPlayers = twoPlayers
+	recordedReplay.WindowWidth = windowWidth
+	recordedReplay.WindowHeight = windowHeight
+	recordedReplay.MyBatW = myBatW
+	recordedReplay.ComputersBatW = computersBatW
+	recordedReplay.BallW = ballW
+	recordedReplay.BallH = ballH
+	keyThisFrame = 0
+	keysHeldThisFrame = 0
+}
+
+// RecordKey remembers which key was pressed on this frame.
+func recordKey(event sdl.Event) {
+	if recording == false {
+		return
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_UP:
+		keyThisFrame = InputUp
+	case sdl.K_DOWN:
+		keyThisFrame = InputDown
+	case sdl.K_PAUSE:
+		keyThisFrame = InputPause
+	}
+}
+
+// RecordHeldKeys remembers which keys two players were holding down on this
+// frame.
+func recordHeldKeys(left, right int) {
+	keysHeldThisFrame = (left&3)<<2 | (right&3)<<4
+}
+
+// RecordFrame adds this frame to the recording. It must be called once every
+// frame while a match is being played on this computer. When the match is
+// over the replay is saved.
+func recordFrame() {
+	if recording == false {
+		return
+	}
+	recordedReplay.Inputs = append(recordedReplay.Inputs, byte(keyThisFrame|keysHeldThisFrame))
+	keyThisFrame = 0
+	keysHeldThisFrame = 0
+	if gameOver == true {
+		saveRecording()
+	}
+}
+
+// SaveRecording saves the replay being recorded, if anything has been played.
+func saveRecording() {
+	recording = false
+	if len(recordedReplay.Inputs) == 0 {
+		return
+	}
+	var filename string
+	filename = filepath.Join(replayDirectory, time.Now().Format("pong-2006-01-02-150405.replay"))
+	var err error
+	err = saveReplay(filename, recordedReplay)
+	if err != nil {
+		fmt.Print("Failed to save the replay: ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Saved the replay to " + filename)
+}
+
+// SaveReplay writes a replay to a file. The replay is squashed with gzip,
+// which is very good at squashing all the frames where nobody pressed a key.
+func saveReplay(filename string, r replay) error {
+	var err error
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	var file *os.File
+	file, err = os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = fmt.Fprintf(file, "%s%d\n", ReplayFilePrefix, ReplayFileVersion)
+	if err != nil {
+		return err
+	}
+	var zipper *gzip.Writer
+	zipper = gzip.NewWriter(file)
+	err = gob.NewEncoder(zipper).Encode(&r)
+	if err != nil {
+		return err
+	}
+	return zipper.Close()
+}
+
+// LoadReplay reads a replay from a file. The version is checked before
+// anything else is read, so a replay from a different version of the game is
+// turned away instead of going wrong halfway through.
+func loadReplay(filename string) (replay, error) {
+	var r replay
+	var file *os.File
+	var err error
+	file, err = os.Open(filename)
+	if err != nil {
+		return r, err
+	}
+	defer file.Close()
+	var reader *bufio.Reader
+	reader = bufio.NewReader(file)
+	var firstLine string
+	firstLine, err = reader.ReadString('\n')
+	if err != nil || strings.HasPrefix(firstLine, ReplayFilePrefix) == false {
+		return r, errors.New(filename + " is not a pong replay")
+	}
+	var version int
+	version, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(firstLine, ReplayFilePrefix)))
+	if err != nil {
+		return r, errors.New(filename + " is not a pong replay")
+	}
+	if version != ReplayFileVersion {
+		return r, fmt.Errorf("the replay is version %d, but this pong can only play version %d replays", version, ReplayFileVersion)
+	}
+	var unzipper *gzip.Reader
+	unzipper, err = gzip.NewReader(reader)
+	if err != nil {
+		return r, err
+	}
+	err = gob.NewDecoder(unzipper).Decode(&r)
+	if err != nil {
+		return r, err
+	}
+	if r.Version != version || r.Settings.StartState == nil {
+		return r, errors.New(filename + " is damaged")
+	}
+	if r.WindowWidth != FieldWidth || r.WindowHeight != FieldHeight {
+		return r, errors.New(filename + " was recorded on a different sized playing field")
+	}
+	// the same checks as a saved match, because a damaged or changed replay
+	// could crash the game as easily
+	err = checkNetSettings(r.Settings)
+	if err != nil {
+		return r, fmt.Errorf("%s is damaged: %v", filename, err)
+	}
+	if r.MyBatW < 1 || r.ComputersBatW < 1 || r.BallW < 1 || r.BallH < 1 ||
+		r.MyBatW > FieldWidth/4 || r.ComputersBatW > FieldWidth/4 || r.BallW > FieldWidth/4 || r.BallH > FieldHeight/4 {
+		return r, errors.New(filename + " is damaged: a bat or the ball is the wrong size")
+	}
+	return r, nil
+}
+
+// ---- Playing back ----
+
+// LoadReplayFromCommandLine loads the replay to play, if the command line
+// asked for one. It returns false if the replay could not be loaded.
+func loadReplayFromCommandLine() bool {
+	if replayFilename == "" {
+		return true
+	}
+	var err error
+	playback, err = loadReplay(replayFilename)
+	if err != nil {
+		fmt.Print("Failed to load the replay: ")
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+// StartPlaybackFromCommandLine starts playing the replay loaded by
+// loadReplayFromCommandLine. It returns false if the replay can't be played.
+func startPlaybackFromCommandLine() bool {
+	if replayFilename == "" {
+		return true
+	}
+	var err error
+	err = startPlayback()
+	if err != nil {
+		fmt.Print("Failed to play the replay: ")
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+// StartPlayback sets the game up the same way as the recorded match, and
+// starts playing it. The start of the match can only be checked once the
+// rules are being used, so if it doesn't make sense the rules are put back
+// the way they were.
+func startPlayback() error {
+	var before *netSettings
+	before = makeNetSettings()
+	applyNetSettings(&playback.Settings)
+	var oldMyBatW, oldComputersBatW, oldBallW, oldBallH int
+	oldMyBatW, oldComputersBatW, oldBallW, oldBallH = myBatW, computersBatW, ballW, ballH
+	myBatW = playback.MyBatW
+	computersBatW = playback.ComputersBatW
+	ballW = playback.BallW
+	ballH = playback.BallH
+	var err error
+	err = checkSavedState(*playback.Settings.StartState)
+	if err != nil {
+		myBatW, computersBatW, ballW, ballH = oldMyBatW, oldComputersBatW, oldBallW, oldBallH
+		applyNetSettings(before)
+		return fmt.Errorf("the start of the replay doesn't make sense: %v", err)
+	}
+	playingBack = true
+	inMenu = false
+	initialiseMyBatPosition()
+	initialiseComputersBatPosition()
+	twoPlayers = playback.TwoPlayers
+	loadGameState(*playback.Settings.StartState)
+	keyframes = []gameState{saveGameState()}
+	playbackFrame = 0
+	playbackPosition = 0
+	playbackSpeed = 2
+	playbackPaused = false
+	return nil
+}
+
+// UpdatePlayback moves the replay on. It must be called once every frame
+// instead of updateState.
+func updatePlayback() {
+	if playbackPaused == true {
+		return
+	}
+	playbackPosition = playbackPosition + playbackSpeeds[playbackSpeed]
+	for playbackFrame < int(playbackPosition) && playbackFrame < len(playback.Inputs) {
+		playRecordedFrame()
+	}
+	// stop at the end
+	if playbackFrame >= len(playback.Inputs) {
+		playbackPaused = true
+		playbackPosition = float64(playbackFrame)
+	}
+}
+
+// PlayRecordedFrame plays one frame of the replay, exactly the same way the
+// game played it when it was recorded.
+func playRecordedFrame() {
+	var input byte
+	input = playback.Inputs[playbackFrame]
+	var key sdl.KeyDownEvent
+	switch int(input & 3) {
+	case InputUp:
+		key.Keysym.Sym = sdl.K_UP
+		handleGameKey(&key)
+	case InputDown:
+		key.Keysym.Sym = sdl.K_DOWN
+		handleGameKey(&key)
+	case InputPause:
+		key.Keysym.Sym = sdl.K_PAUSE
+		handleGameKey(&key)
+	}
+	playbackLeftHeld = int(input>>2) & 3
+	playbackRightHeld = int(input>>4) & 3
+	if paused == false {
+		updateState()
+	}
+	playbackFrame = playbackFrame + 1
+	// save a keyframe, if we haven't got this one already
+	if playbackFrame%KeyframeInterval == 0 && playbackFrame/KeyframeInterval == len(keyframes) {
+		keyframes = append(keyframes, saveGameState())
+	}
+}
+
+// SeekPlayback jumps to a frame of the replay. To go backwards we load the
+// keyframe before the frame we want and play forwards from there.
+func seekPlayback(frame int) {
+	if frame < 0 {
+		frame = 0
+	}
+	if frame > len(playback.Inputs) {
+		frame = len(playback.Inputs)
+	}
+	var k int
+	k = frame / KeyframeInterval
+	if k >= len(keyframes) {
+		k = len(keyframes) - 1
+	}
+	if frame < playbackFrame || k*KeyframeInterval > playbackFrame {
+		loadGameState(keyframes[k])
+		playbackFrame = k * KeyframeInterval
+	}
+	for playbackFrame < frame {
+		playRecordedFrame()
+	}
+	playbackPosition = float64(playbackFrame)
+}
+
+// HandlePlaybackEvent responds to the keys pressed while a replay is playing.
+func handlePlaybackEvent(event sdl.Event) {
+	if isKeyDownEvent(event) == false {
+		return
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_ESCAPE:
+		quit = true
+	case sdl.K_SPACE, sdl.K_PAUSE:
+		if playbackPaused == true {
+			playbackPaused = false
+		} else {
+			playbackPaused = true
+		}
+		// playing from the end starts again
+		if playbackPaused == false && playbackFrame >= len(playback.Inputs) {
+			seekPlayback(0)
+		}
+	case sdl.K_PERIOD:
+		// step forwards one frame
+		playbackPaused = true
+		seekPlayback(playbackFrame + 1)
+	case sdl.K_COMMA:
+		// step backwards one frame
+		playbackPaused = true
+		seekPlayback(playbackFrame - 1)
+	case sdl.K_LEFT:
+		seekPlayback(playbackFrame - SeekFrames)
+	case sdl.K_RIGHT:
+		seekPlayback(playbackFrame + SeekFrames)
+	case sdl.K_HOME:
+		seekPlayback(0)
+	case sdl.K_UP:
+		if playbackSpeed < len(playbackSpeeds)-1 {
+			playbackSpeed = playbackSpeed + 1
+		}
+	case sdl.K_DOWN:
+		if playbackSpeed > 0 {
+			playbackSpeed = playbackSpeed - 1
+		}
+	}
+}
+
+// RenderPlaybackStatus shows where the replay has got to and how fast it is
+// playing.
+func renderPlaybackStatus() {
+	var at, length time.Duration
+	at = time.Duration(playbackFrame) * time.Second / UpdatesPerSecond
+	length = time.Duration(len(playback.Inputs)) * time.Second / UpdatesPerSecond
+	var status string
+	status = "REPLAY " + formatClock(at) + " / " + formatClock(length) +
+		" X" + strconv.FormatFloat(playbackSpeeds[playbackSpeed], 'g', -1, 64)
+	if playbackPaused == true {
+		status = status + " PAUSED"
+	}
+	renderTextCentred(status, windowWidth/2, windowHeight-64, 3, 255, 255, 0)
+	renderTextCentred("SPACE PAUSE, LEFT/RIGHT SEEK, UP/DOWN SPEED, COMMA/PERIOD STEP, ESCAPE QUIT",
+		windowWidth/2, windowHeight-28, 2, 128, 128, 128)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLoadReplay(t *testing.T) {
+	var tests = []struct {
+		name   string
+		change func(r *replay)
+		ok     bool
+	}{
+		{"a good replay", func(r *replay) {}, true},
+		{"a version that doesn't match the first line", func(r *replay) { r.Version = ReplayFileVersion + 1 }, false},
+		{"no starting state", func(r *replay) { r.Settings.StartState = nil }, false},
+		{"a different sized field", func(r *replay) { r.WindowWidth = FieldWidth / 2 }, false},
+		{"an unknown arena", func(r *replay) { r.Settings.ArenaNumber = -1 }, false},
+		{"a bat that is too small", func(r *replay) { r.Settings.MyBatH = 1 }, false},
+		{"a bat with no width", func(r *replay) { r.MyBatW = 0 }, false},
+		{"a ball that is too big", func(r *replay) { r.BallH = FieldHeight }, false},
+	}
+	var test struct {
+		name   string
+		change func(r *replay)
+		ok     bool
+	}
+	var filename string
+	filename = filepath.Join(t.TempDir(), "test.replay")
+	for _, test = range tests {
+		setUpTestMatch(t)
+		var r replay
+		r.Version = ReplayFileVersion
+		r.Settings = *makeNetSettings()
+		var state gameState
+		state = saveGameState()
+		r.Settings.StartState = &state
+		r.WindowWidth = FieldWidth
+		r.WindowHeight = FieldHeight
+		r.MyBatW = myBatW
+		r.ComputersBatW = computersBatW
+		r.BallW = ballW
+		r.BallH = ballH
+		r.Inputs = make([]byte, UpdatesPerSecond)
+		test.change(&r)
+		var err error
+		err = saveReplay(filename, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = loadReplay(filename)
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: loaded a replay it should have turned away", test.name)
+		}
+	}
+}
+
+func TestLoadReplayFirstLine(t *testing.T) {
+	var tests = []struct {
+		name     string
+		contents string
+	}{
+		{"an empty file", ""},
+		{"not a replay", "hello\n"},
+		{"no version", ReplayFilePrefix + "\n"},
+		{"a different version", ReplayFilePrefix + "1\n"},
+		{"nothing after the first line", ReplayFilePrefix + strconv.Itoa(ReplayFileVersion) + "\n"},
+	}
+	var test struct {
+		name     string
+		contents string
+	}
+	var filename string
+	filename = filepath.Join(t.TempDir(), "test.replay")
+	for _, test = range tests {
+		writeTestFile(t, filename, []byte(test.contents))
+		var err error
+		_, err = loadReplay(filename)
+		if err == nil {
+			t.Errorf("%s: loaded a replay it should have turned away", test.name)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Results and records ----
+//
+// The result of every match played on this computer is saved, so the
+// records screen can show who has won the most matches, the longest rallies
+// and each player's recent matches. The results are saved in a JSON file in
+// the player's settings folder.
+
+// The version of the results file. If the file changes, this must change
+// too, so an old file is not read wrongly.
+const ResultsFileVersion = 1
+
+// The name of the file the results are saved in.
+const ResultsFilename = "results.json"
+
+// The most results we keep. When there are more, the oldest are forgotten.
+const MaximumResults = 1000
+
+// The name used for the computer when it plays.
+const ComputerName = "COMPUTER"
+
+// How many lines each part of the records screen shows.
+const RecordsTableLines = 6
+
+// A matchResult is the result of one match. It is saved as JSON, so the names
+// start with capital letters.
+type matchResult struct {
+	Player   string
+	Opponent string
+	// the difficulty of the computer, or "" if the opponent was a person
+	Difficulty    string
+	PlayerScore   int
+	OpponentScore int
+	// the most times the ball was hit in one rally
+	LongestRally int
+	Seconds      int
+	Played       time.Time
+}
+
+// The results file.
+type resultsFile struct {
+	Version int
+	Results []matchResult
+}
+
+// The resultRecorded flag is true once the match's result has been saved, so
+// it is only saved once.
+var resultRecorded bool
+
+// The inRecordsScreen flag is true while the records screen is showing. The
+// records screen is part of the menu, so inMenu is true as well.
+var inRecordsScreen bool
+
+// The results shown on the records screen.
+var shownResults []matchResult
+var resultsMessage string
+
+// ---- Keeping track of the match ----
+
+// StartMatchRecord gets ready to record the result of a new match.
+func startMatchRecord() {
+	resultRecorded = false
+}
+
+// MatchPlayerNames works out the names of the left and right players. In a
+// tournament they are the names typed on the tournament screen. Otherwise the
+// left player is the player whose profile is chosen, playing the computer.
+func matchPlayerNames() (string, string) {
+	if playingTournamentMatch == true {
+		var m tournamentMatch
+		m = theTournament.Matches[tournamentMatchNumber]
+		return theTournament.Players[m.Left].Name, theTournament.Players[m.Right].Name
+	}
+	return playersProfile().Name, ComputerName
+}
+
+// RecordResultIfOver saves the result once the match is over. It is only
+// called for matches played on this computer.
+func recordResultIfOver() {
+	if gameOver == false || resultRecorded == true {
+		return
+	}
+	resultRecorded = true
+	var r matchResult
+	r.Player, r.Opponent = matchPlayerNames()
+	r.Difficulty = difficultyNames[difficulty]
+	if twoPlayers == true {
+		r.Difficulty = ""
+	}
+	r.PlayerScore = myScore
+	r.OpponentScore = computersScore
+	r.LongestRally = stats.LongestRally
+	r.Seconds = stats.Frames / UpdatesPerSecond
+	r.Played = time.Now()
+	saveResult(r)
+}
+
+// ---- Saving the results ----
+
+// SaveResult adds a result to the results file.
+func saveResult(r matchResult) {
+	var f resultsFile
+	var err error
+	f, err = loadResults()
+	if err != nil && os.IsNotExist(err) == false {
+		// don't overwrite a file we can't read, somebody might want it
+		fmt.Print("Failed to load the results: ")
+		fmt.Println(err)
+		return
+	}
+	f.Version = ResultsFileVersion
+	f.Results = append(f.Results, r)
+	if len(f.Results) > MaximumResults {
+		f.Results = f.Results[len(f.Results)-MaximumResults:]
+	}
+	var data []byte
+	data, err = json.MarshalIndent(f, "", "  ")
+	if err == nil {
+		err = os.WriteFile(userFilePath(ResultsFilename), data, 0644)
+	}
+	if err != nil {
+		fmt.Print("Failed to save the result: ")
+		fmt.Println(err)
+	}
+}
+
+// LoadResults loads the results file, and checks it makes sense.
+func loadResults() (resultsFile, error) {
+	var f resultsFile
+	var data []byte
+	var err error
+	data, err = os.ReadFile(userFilePath(ResultsFilename))
+	if err != nil {
+		return f, err
+	}
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return f, err
+	}
+	if f.Version != ResultsFileVersion {
+		return f, errors.New("the results were saved by a different version of pong")
+	}
+	var r matchResult
+	for _, r = range f.Results {
+		if r.Player == "" || r.Opponent == "" || r.PlayerScore < 0 || r.OpponentScore < 0 ||
+			r.LongestRally < 0 || r.Seconds < 0 {
+			return f, errors.New("the results file is damaged")
+		}
+	}
+	return f, nil
+}
+
+// ---- The records screen ----
+
+// OpenRecordsScreen loads the results and shows the records screen.
+func openRecordsScreen() {
+	inRecordsScreen = true
+	resultsMessage = ""
+	var f resultsFile
+	var err error
+	f, err = loadResults()
+	shownResults = f.Results
+	if err != nil && os.IsNotExist(err) == false {
+		resultsMessage = "COULD NOT READ THE RESULTS"
+		shownResults = nil
+	} else if len(shownResults) == 0 {
+		resultsMessage = "NO MATCHES HAVE BEEN PLAYED YET"
+	}
+}
+
+// HandleRecordsEvent goes back to the menu when escape or return is pressed.
+func handleRecordsEvent(event sdl.Event) {
+	if isKeyDownEvent(event) == false {
+		return
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_ESCAPE, sdl.K_RETURN:
+		inRecordsScreen = false
+	}
+}
+
+// A playerRecord adds up all of one player's results.
+type playerRecord struct {
+	name         string
+	played       int
+	won          int
+	longestRally int
+}
+
+// PlayerRecords adds up everybody's results, and sorts them so the player
+// who has won the most matches is first. The computer isn't included.
+func playerRecords(results []matchResult) []playerRecord {
+	var records []playerRecord
+	var find = func(name string) *playerRecord {
+		var i int
+		for i = 0; i < len(records); i++ {
+			if records[i].name == name {
+				return &records[i]
+			}
+		}
+		records = append(records, playerRecord{name: name})
+		return &records[len(records)-1]
+	}
+	var r matchResult
+	for _, r = range results {
+		var p *playerRecord
+		p = find(r.Player)
+		p.played = p.played + 1
+		if r.PlayerScore > r.OpponentScore {
+			p.won = p.won + 1
+		}
+		if r.LongestRally > p.longestRally {
+			p.longestRally = r.LongestRally
+		}
+		// when two people played, the opponent has a result too
+		if r.Difficulty == "" {
+			p = find(r.Opponent)
+			p.played = p.played + 1
+			if r.OpponentScore > r.PlayerScore {
+				p.won = p.won + 1
+			}
+			if r.LongestRally > p.longestRally {
+				p.longestRally = r.LongestRally
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].won != records[j].won {
+			return records[i].won > records[j].won
+		}
+		return records[i].played < records[j].played
+	})
+	return records
+}
+
+// LongestRallies finds the matches with the longest rallies.
+func longestRallies(results []matchResult) []matchResult {
+	var sorted []matchResult
+	sorted = append(sorted, results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LongestRally > sorted[j].LongestRally
+	})
+	return sorted
+}
+
+// RecentResults finds a player's most recent matches, newest first.
+func recentResults(results []matchResult, name string) []matchResult {
+	var recent []matchResult
+	var i int
+	for i = len(results) - 1; i >= 0; i-- {
+		if results[i].Player == name || results[i].Opponent == name {
+			recent = append(recent, results[i])
+		}
+	}
+	return recent
+}
+
+// RenderRecordsScreen draws the records screen.
+func renderRecordsScreen() {
+	renderTextCentred("RECORDS", windowWidth/2, 24, 8, 255, 255, 255)
+	var y int
+	y = 110
+	renderText("MOST WINS", 60, y, 3, 255, 255, 0)
+	renderText("PLAYED  WON  BEST RALLY", 400, y+6, 2, 128, 128, 128)
+	var records []playerRecord
+	records = playerRecords(shownResults)
+	var i int
+	for i = 0; i < len(records) && i < RecordsTableLines; i++ {
+		var p playerRecord
+		p = records[i]
+		var lineY int
+		lineY = y + 30 + i*22
+		renderText(p.name, 60, lineY, 2, 255, 255, 255)
+		renderText(strconv.Itoa(p.played), 400, lineY, 2, 255, 255, 255)
+		renderText(strconv.Itoa(p.won), 496, lineY, 2, 255, 255, 255)
+		renderText(strconv.Itoa(p.longestRally), 556, lineY, 2, 255, 255, 255)
+	}
+
+	y = 290
+	renderText("LONGEST RALLIES", 60, y, 3, 255, 255, 0)
+	var rallies []matchResult
+	rallies = longestRallies(shownResults)
+	for i = 0; i < len(rallies) && i < RecordsTableLines; i++ {
+		var lineY int
+		lineY = y + 30 + i*22
+		renderText(strconv.Itoa(rallies[i].LongestRally)+" HITS", 60, lineY, 2, 255, 255, 255)
+		renderText(rallies[i].Player+" V "+rallies[i].Opponent, 200, lineY, 2, 255, 255, 255)
+		renderText(rallies[i].Played.Format("2006-01-02"), 700, lineY, 2, 128, 128, 128)
+	}
+
+	y = 470
+	var name string
+	name = playersProfile().Name
+	renderText("RECENT MATCHES FOR "+name, 60, y, 3, 255, 255, 0)
+	renderText("SCORE  RALLY  TIME", 560, y+30, 2, 128, 128, 128)
+	var recent []matchResult
+	recent = recentResults(shownResults, name)
+	for i = 0; i < len(recent) && i < RecordsTableLines; i++ {
+		renderRecentResult(recent[i], name, y+52+i*22)
+	}
+
+	if resultsMessage != "" {
+		renderTextCentred(resultsMessage, windowWidth/2, windowHeight-90, 3, 255, 128, 0)
+	}
+	renderTextCentred("ESCAPE FOR THE MENU", windowWidth/2, windowHeight-40, 2, 128, 128, 128)
+}
+
+// RenderRecentResult draws one of a player's recent matches, from their side
+// of the table.
+func renderRecentResult(r matchResult, name string, y int) {
+	var opponent string
+	var mine, theirs int
+	if r.Player == name {
+		opponent = r.Opponent
+		mine = r.PlayerScore
+		theirs = r.OpponentScore
+	} else {
+		opponent = r.Player
+		mine = r.OpponentScore
+		theirs = r.PlayerScore
+	}
+	if r.Difficulty != "" {
+		opponent = opponent + " (" + r.Difficulty + ")"
+	}
+	var won string
+	if mine > theirs {
+		won = "WON"
+	} else {
+		won = "LOST"
+	}
+	var seconds time.Duration
+	seconds = time.Duration(r.Seconds) * time.Second
+	renderText(won+" V "+opponent, 60, y, 2, 255, 255, 255)
+	renderText(strconv.Itoa(mine)+"-"+strconv.Itoa(theirs), 560, y, 2, 255, 255, 255)
+	renderText(strconv.Itoa(r.LongestRally), 644, y, 2, 255, 255, 255)
+	renderText(formatClock(seconds), 728, y, 2, 255, 255, 255)
+}
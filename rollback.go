@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Rollback netcode ----
+//
+// When the host runs the whole game, the joining player has to wait for their
+// key presses to reach the host and for the new state to come back before
+// they see their bat move. On a slow network that makes their bat feel laggy.
+//
+// Rollback works differently. Both computers run the whole game, and the only
+// thing they send each other is which keys their player is holding down on
+// each frame. Because the game is deterministic, if both computers start
+// from the same game state and use the same keys they will always agree.
+//
+// Of course we don't know which keys the other player is holding down until
+// their message arrives. So we guess - we predict they are still holding the
+// same keys as the last time we heard from them - and carry on. Most of the
+// time the guess is right. When it is wrong we roll back: we load the game
+// state from the frame we guessed wrong, and play the frames again, very
+// quickly, with the right keys.
+//
+// The key messages are sent over UDP rather than TCP. UDP messages can get
+// lost, so every message repeats all of the keys the other computer has not
+// told us it has received yet.
+
+// The ways a network game can be run. The host can run the whole game, or
+// both computers can run it using rollback.
+const NetcodeHost = 0
+const NetcodeRollback = 1
+
+// The netcode the host has chosen, and its name from the command line
+var netcode int
+var netcodeName string
+
+// The rollbackActive flag is true while we are playing a network game using
+// rollback.
+var rollbackActive bool
+
+// The keys a player can be holding down on a frame. Each key is one bit of
+// the input, so the player can be holding down more than one at a time.
+const TickInputUp = 1
+const TickInputDown = 2
+
+// The pause key is different, it is only in the input on the frame it was
+// pressed.
+const TickInputPause = 4
+
+// How many frames of past inputs and game states we remember. This must be
+// much bigger than MaxPredictionFrames.
+const RollbackBufferSize = 256
+
+// The furthest we let the game run ahead of the inputs we have received from
+// the other player. If we get this far ahead we wait for them to catch up.
+const MaxPredictionFrames = 10
+
+// The most inputs we put into one message.
+const MaxInputsPerPacket = 64
+
+// Every rollback message starts with these bytes, so we can ignore anything
+// else that arrives.
+const RollbackMagic1 = 'P'
+const RollbackMagic2 = 'R'
+
+// The number of frames a players key presses are delayed by. A small delay
+// means we have to guess less often, but too much makes the game feel slow.
+var inputDelay int
+
+// These make the network worse on purpose, so we can try rollback out on one
+// computer. Every message is delayed by the simulated latency, and the
+// simulated loss is the chance that a message is thrown away.
+var simulatedLatency time.Duration
+var simulatedLoss float64
+
+// The UDP connection the inputs are sent over, and the address of the other
+// computer.
+var rollbackConn *net.UDPConn
+var rollbackPeer *net.UDPAddr
+
+// The messages that arrive from the other computer.
+var rollbackPackets chan rollbackPacket
+
+// The side of the field this computers player is on, Player or Computer.
+var localSide int
+
+// The frame we will simulate next.
+var currentFrame int
+
+// The resimulating flag is true while frames are being played again after a
+// wrong guess.
+var resimulating bool
+
+// Our players inputs, and the highest frame we have an input for. Our
+// inputs are for frames in the future because of the input delay.
+var localInputs [RollbackBufferSize]int
+var localInputsUpTo int
+
+// The other players inputs, and the frame each one is for. A frame of -1 means
+// we don't have an input for that place in the buffer.
+var remoteInputs [RollbackBufferSize]int
+var remoteInputFrames [RollbackBufferSize]int
+
+// The input we used for the other player when we simulated each frame. It was
+// either their real input, or our guess.
+var usedRemoteInputs [RollbackBufferSize]int
+
+// The game state at the start of each frame, so we can go back to it.
+var snapshots [RollbackBufferSize]gameState
+
+// We have every input from the other player up to and including this frame.
+var confirmedRemoteFrame int
+
+// The other player has every input from us up to and including this frame.
+var remoteAckedFrame int
+
+// The earliest frame we guessed wrong on, or -1 if we have not guessed wrong.
+var rollbackTo int
+
+// The pause key was pressed since the last frame.
+var pausePressed bool
+
+// The number of frames in a row we have had to wait for the other player.
+var stalledFrames int
+
+// A rollbackPacket is a message from the other computer.
+type rollbackPacket struct {
+	ack        int
+	firstFrame int
+	inputs     []int
+}
+
+// AddRollbackFlags adds the command line flags for rollback.
+func addRollbackFlags() {
+	flag.StringVar(&netcodeName, "netcode", "host", "how the host runs a network game: host or rollback")
+	flag.IntVar(&inputDelay, "input-delay", 2, "the number of frames your key presses are delayed by in a rollback game")
+	flag.DurationVar(&simulatedLatency, "net-latency", 0, "delay every rollback message by this long, to test on one computer")
+	flag.Float64Var(&simulatedLoss, "net-loss", 0, "the chance, from 0 to 1, that a rollback message is lost, to test on one computer")
+}
+
+// CheckRollbackFlags makes sure the rollback flags make sense.
+func checkRollbackFlags() error {
+	if netcodeName == "host" {
+		netcode = NetcodeHost
+	} else if netcodeName == "rollback" {
+		netcode = NetcodeRollback
+	} else {
+		return badSetting("netcode", "unknown netcode "+netcodeName)
+	}
+	if inputDelay < 0 || inputDelay > MaxPredictionFrames {
+		return badSetting("input-delay", fmt.Sprintf("the input delay must be between 0 and %d frames", MaxPredictionFrames))
+	} else if simulatedLatency < 0 {
+		return badSetting("net-latency", "the simulated latency cannot be less than zero")
+	} else if simulatedLoss < 0 || simulatedLoss >= 1 {
+		return badSetting("net-loss", "the simulated loss must be between 0 and 1")
+	}
+	return nil
+}
+
+// OpenRollbackConn opens the UDP connection the inputs will be sent over. Any
+// free port will do, we tell the other computer which one we got.
+func openRollbackConn() (int, error) {
+	var err error
+	rollbackConn, err = net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		return 0, err
+	}
+	return rollbackConn.LocalAddr().(*net.UDPAddr).Port, nil
+}
+
+// CloseRollbackConn closes the UDP connection.
+func closeRollbackConn() {
+	if rollbackConn != nil {
+		rollbackConn.Close()
+		rollbackConn = nil
+	}
+}
+
+// StartRollbackSession starts playing with rollback. The game state must
+// already be the same on both computers.
+func startRollbackSession(peer *net.UDPAddr, side int) {
+	rollbackPeer = peer
+	localSide = side
+	rollbackPackets = make(chan rollbackPacket, RollbackBufferSize)
+	go receiveRollbackPackets(rollbackConn, rollbackPackets)
+
+	currentFrame = 0
+	// we have no key presses for the first few frames because of the input
+	// delay, so we don't press any keys on those frames
+	var i int
+	for i = 0; i < RollbackBufferSize; i++ {
+		localInputs[i] = 0
+		remoteInputs[i] = 0
+		remoteInputFrames[i] = -1
+		usedRemoteInputs[i] = 0
+	}
+	localInputsUpTo = inputDelay - 1
+	confirmedRemoteFrame = -1
+	remoteAckedFrame = -1
+	rollbackTo = -1
+	pausePressed = false
+	stalledFrames = 0
+	rollbackActive = true
+}
+
+// StopRollbackSession stops playing with rollback.
+func stopRollbackSession() {
+	rollbackActive = false
+	closeRollbackConn()
+}
+
+// HandleRollbackEvent deals with the keys pressed during a rollback game. The
+// cursor keys are read once every frame, so the only key we need to notice
+// here is the pause key.
+func handleRollbackEvent(event sdl.Event) {
+	if isKeyDownEvent(event) && isKeyPause(event) {
+		pausePressed = true
+	}
+}
+
+// ReadLocalInput works out which keys our player is holding down.
+func readLocalInput() int {
+	var input int
+	var keys []uint8
+	keys = sdl.GetKeyboardState()
+	if len(keys) > int(sdl.SCANCODE_DOWN) {
+		if keys[sdl.SCANCODE_UP] != 0 {
+			input = input | TickInputUp
+		}
+		if keys[sdl.SCANCODE_DOWN] != 0 {
+			input = input | TickInputDown
+		}
+	}
+	if pausePressed == true {
+		input = input | TickInputPause
+		pausePressed = false
+	}
+	return input
+}
+
+// RollbackTick moves the rollback game on by one frame. It must be called once
+// every frame instead of updateState.
+func rollbackTick() {
+	readRollbackPackets()
+	// if we guessed wrong, go back and play the frames again
+	if rollbackTo >= 0 {
+		resimulateFrom(rollbackTo)
+		rollbackTo = -1
+	}
+	// we can only carry on if we are not too far ahead of the other player
+	if remotePlayer != nil && currentFrame-confirmedRemoteFrame <= MaxPredictionFrames {
+		localInputsUpTo = localInputsUpTo + 1
+		localInputs[localInputsUpTo%RollbackBufferSize] = readLocalInput()
+		simulateFrame(currentFrame)
+		currentFrame = currentFrame + 1
+		stalledFrames = 0
+	} else {
+		stalledFrames = stalledFrames + 1
+	}
+	if stalledFrames > UpdatesPerSecond/2 {
+		networkStatus = "WAITING FOR THE OTHER PLAYER"
+	} else if networkStatus == "WAITING FOR THE OTHER PLAYER" {
+		networkStatus = ""
+	}
+	sendRollbackInputs()
+}
+
+// SimulateFrame saves the game state and then plays one frame.
+func simulateFrame(frame int) {
+	var slot int
+	slot = frame % RollbackBufferSize
+	snapshots[slot] = saveGameState()
+	var remote int
+	remote = remoteInputFor(frame)
+	usedRemoteInputs[slot] = remote
+	if localSide == Player {
+		stepSimulation(localInputs[slot], remote)
+	} else {
+		stepSimulation(remote, localInputs[slot])
+	}
+}
+
+// ResimulateFrom loads the game state from a frame we guessed wrong on, and
+// plays every frame from there up to now again.
+func resimulateFrom(frame int) {
+	loadGameState(snapshots[frame%RollbackBufferSize])
+	resimulating = true
+	var f int
+	for f = frame; f < currentFrame; f++ {
+		simulateFrame(f)
+	}
+	resimulating = false
+}
+
+// RemoteInputFor returns the other players input for a frame. If we don't
+// have it yet we guess they are still holding the same keys as in the last
+// input we do have. We never guess that they pressed pause.
+func remoteInputFor(frame int) int {
+	var slot int
+	slot = frame % RollbackBufferSize
+	if remoteInputFrames[slot] == frame {
+		return remoteInputs[slot]
+	}
+	if confirmedRemoteFrame < 0 {
+		return 0
+	}
+	return remoteInputs[confirmedRemoteFrame%RollbackBufferSize] &^ TickInputPause
+}
+
+// StepSimulation plays one frame of the game with the inputs for the left and
+// right players.
+func stepSimulation(leftInput, rightInput int) {
+	if (leftInput|rightInput)&TickInputPause != 0 && gameOver == false {
+		paused = !paused
+	}
+	if paused == true || gameOver == true {
+		return
+	}
+	myBatY = moveBatForInput(myBatY, myBatH, myBatStep(), leftInput)
+	computersBatY = moveBatForInput(computersBatY, computersBatH, computersBatStep(), rightInput)
+	updateState()
+}
+
+// MoveBatForInput moves a bat up or down for one frame, and makes sure it does
+// not go off the screen. A held key moves the bat as far as 30 key presses
+// a second would, which is about how fast a key repeats.
+func moveBatForInput(y, h, step, input int) int {
+	var distance int
+	distance = step * 30 / UpdatesPerSecond
+	if input&TickInputUp != 0 {
+		y = y - distance
+	}
+	if input&TickInputDown != 0 {
+		y = y + distance
+	}
+	if y < 0 {
+		y = 0
+	}
+	if y+h > windowHeight {
+		y = windowHeight - h
+	}
+	return y
+}
+
+// ---- Sending and receiving inputs ----
+
+// SendRollbackInputs sends the other player every input they have not told us
+// they have received. Sending them again is how we cope with lost messages.
+// A message looks like this:
+//
+//	'P' 'R' version ack(4 bytes) firstFrame(4 bytes) count(1 byte) inputs(count bytes)
+func sendRollbackInputs() {
+	var first int
+	first = remoteAckedFrame + 1
+	if localInputsUpTo-first+1 > MaxInputsPerPacket {
+		first = localInputsUpTo - MaxInputsPerPacket + 1
+	}
+	var count int
+	count = localInputsUpTo - first + 1
+	if count < 0 {
+		count = 0
+	}
+	var packet []byte
+	packet = make([]byte, 12+count)
+	packet[0] = RollbackMagic1
+	packet[1] = RollbackMagic2
+	packet[2] = NetworkProtocolVersion
+	binary.BigEndian.PutUint32(packet[3:7], uint32(int32(confirmedRemoteFrame)))
+	binary.BigEndian.PutUint32(packet[7:11], uint32(int32(first)))
+	packet[11] = byte(count)
+	var i int
+	for i = 0; i < count; i++ {
+		packet[12+i] = byte(localInputs[(first+i)%RollbackBufferSize])
+	}
+	sendRollbackPacket(packet)
+}
+
+// SendRollbackPacket sends a message, unless we are simulating a bad network
+// and decide to lose it or delay it.
+func sendRollbackPacket(packet []byte) {
+	if simulatedLoss > 0 && rand.Float64() < simulatedLoss {
+		return
+	}
+	var conn = rollbackConn
+	var peer = rollbackPeer
+	if conn == nil {
+		return
+	}
+	if simulatedLatency > 0 {
+		time.AfterFunc(simulatedLatency, func() {
+			conn.WriteToUDP(packet, peer)
+		})
+		return
+	}
+	conn.WriteToUDP(packet, peer)
+}
+
+// ReceiveRollbackPackets reads messages until the connection is closed.
+func receiveRollbackPackets(conn *net.UDPConn, packets chan rollbackPacket) {
+	var buffer [512]byte
+	for {
+		var n int
+		var err error
+		n, _, err = conn.ReadFromUDP(buffer[:])
+		if err != nil {
+			return
+		}
+		var packet rollbackPacket
+		var ok bool
+		packet, ok = parseRollbackPacket(buffer[:n])
+		if ok {
+			select {
+			case packets <- packet:
+			default:
+			}
+		}
+	}
+}
+
+// ParseRollbackPacket reads a message. It returns false if the message is not
+// a rollback message from the same version of the game.
+func parseRollbackPacket(data []byte) (rollbackPacket, bool) {
+	var packet rollbackPacket
+	if len(data) < 12 || data[0] != RollbackMagic1 || data[1] != RollbackMagic2 || data[2] != NetworkProtocolVersion {
+		return packet, false
+	}
+	packet.ack = int(int32(binary.BigEndian.Uint32(data[3:7])))
+	packet.firstFrame = int(int32(binary.BigEndian.Uint32(data[7:11])))
+	var count int
+	count = int(data[11])
+	if len(data) < 12+count {
+		return packet, false
+	}
+	var i int
+	for i = 0; i < count; i++ {
+		packet.inputs = append(packet.inputs, int(data[12+i]))
+	}
+	return packet, true
+}
+
+// ReadRollbackPackets stores the inputs from every message that has arrived,
+// and notices if any of them are different from what we guessed.
+func readRollbackPackets() {
+	for {
+		select {
+		case packet := <-rollbackPackets:
+			storeRemoteInputs(packet)
+		default:
+			return
+		}
+	}
+}
+
+// StoreRemoteInputs stores the inputs from one message.
+func storeRemoteInputs(packet rollbackPacket) {
+	if packet.ack > remoteAckedFrame {
+		remoteAckedFrame = packet.ack
+	}
+	var i int
+	for i = 0; i < len(packet.inputs); i++ {
+		var frame int
+		frame = packet.firstFrame + i
+		// ignore inputs we already have, and inputs so far ahead they would
+		// not fit in the buffer
+		if frame <= confirmedRemoteFrame || frame >= currentFrame+RollbackBufferSize/2 {
+			continue
+		}
+		var slot int
+		slot = frame % RollbackBufferSize
+		if remoteInputFrames[slot] == frame {
+			continue
+		}
+		remoteInputs[slot] = packet.inputs[i]
+		remoteInputFrames[slot] = frame
+		// if we have already played this frame with a guess, and the guess
+		// was wrong, we need to roll back to it
+		if frame < currentFrame && usedRemoteInputs[slot] != packet.inputs[i] {
+			if rollbackTo < 0 || frame < rollbackTo {
+				rollbackTo = frame
+			}
+		}
+	}
+	// move the confirmed frame on as far as we have every input
+	for remoteInputFrames[(confirmedRemoteFrame+1)%RollbackBufferSize] == confirmedRemoteFrame+1 {
+		confirmedRemoteFrame = confirmedRemoteFrame + 1
+	}
+}
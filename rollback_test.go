@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRollbackPacket(t *testing.T) {
+	var packet []byte
+	packet = []byte{RollbackMagic1, RollbackMagic2, NetworkProtocolVersion, 0, 0, 0, 5, 0, 0, 0, 9, 2, 1, 2}
+	var p rollbackPacket
+	var ok bool
+	p, ok = parseRollbackPacket(packet)
+	if ok == false || p.ack != 5 || p.firstFrame != 9 || len(p.inputs) != 2 || p.inputs[0] != 1 || p.inputs[1] != 2 {
+		t.Errorf("read the packet as %+v, %v", p, ok)
+	}
+	// a packet from another version, or one that has been cut short, is
+	// ignored
+	packet[2] = NetworkProtocolVersion + 1
+	_, ok = parseRollbackPacket(packet)
+	if ok == true {
+		t.Error("read a packet from another version")
+	}
+	packet[2] = NetworkProtocolVersion
+	_, ok = parseRollbackPacket(packet[:13])
+	if ok == true {
+		t.Error("read a packet that was cut short")
+	}
+}
+
+func TestMoveBatForInput(t *testing.T) {
+	windowHeight = FieldHeight
+	var step, distance int
+	step = 20
+	distance = step * 30 / UpdatesPerSecond
+	var tests = []struct {
+		name  string
+		y     int
+		input int
+		want  int
+	}{
+		{"no keys", 300, 0, 300},
+		{"up", 300, TickInputUp, 300 - distance},
+		{"down", 300, TickInputDown, 300 + distance},
+		{"both keys", 300, TickInputUp | TickInputDown, 300},
+		{"up at the top", 2, TickInputUp, 0},
+		{"down at the bottom", FieldHeight - 100 - 2, TickInputDown, FieldHeight - 100},
+	}
+	var test struct {
+		name  string
+		y     int
+		input int
+		want  int
+	}
+	for _, test = range tests {
+		var got int
+		got = moveBatForInput(test.y, 100, step, test.input)
+		if got != test.want {
+			t.Errorf("%s: the bat moved to %d, not %d", test.name, got, test.want)
+		}
+	}
+}
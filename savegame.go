@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ---- Saving a match to finish later ----
+//
+// If the game is closed in the middle of a match, the match is saved. The
+// next time the game starts "CONTINUE" on the menu carries on from exactly
+// where it stopped: the scores, where the bats and the ball are, the rules,
+// the difficulty and even the random number generator that decides which way
+// the ball is served.
+//
+// The match is saved as JSON in the player's settings folder. The file is
+// checked carefully when it is loaded, because a file that has been changed
+// by hand could put the ball somewhere the game doesn't expect.
+
+// The version of the save file. If the file changes, this must change too, so
+// an old file is not read wrongly.
+const SaveFileVersion = 4
+
+// The name of the file the match is saved in.
+const SaveFilename = "match.json"
+
+// A savedMatch is everything needed to carry on a match. It is saved as JSON,
+// so the names start with capital letters.
+type savedMatch struct {
+	Version    int
+	Settings   netSettings
+	State      gameState
+	Statistics matchStatistics
+}
+
+// The haveSavedMatch flag is true if there is a saved match to continue.
+var haveSavedMatch bool
+
+// CheckForSavedMatch looks to see if there is a saved match that can be
+// continued.
+func checkForSavedMatch() {
+	var err error
+	_, err = loadMatch()
+	haveSavedMatch = err == nil
+	if err != nil && os.IsNotExist(err) == false {
+		fmt.Print("Failed to load the saved match: ")
+		fmt.Println(err)
+	}
+}
+
+// MatchInProgress is true while a match is being played on this computer and
+// isn't over yet. Tournament matches aren't saved, because the tournament
+// saves itself between matches.
+func matchInProgress() bool {
+	return inMenu == false && inNetworkScreen == false && inTournamentScreen == false &&
+		networkRole == NotNetworked && playingBack == false &&
+		playingTournamentMatch == false && gameOver == false
+}
+
+// SaveMatchOnQuit saves the match if the game is closed in the middle of it.
+func saveMatchOnQuit() {
+	if matchInProgress() == false {
+		return
+	}
+	var err error
+	err = saveMatch()
+	if err != nil {
+		fmt.Print("Failed to save the match: ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Saved the match. Choose CONTINUE on the menu to finish it.")
+}
+
+// SaveMatch saves the match being played.
+func saveMatch() error {
+	var m savedMatch
+	m.Version = SaveFileVersion
+	m.Settings = *makeNetSettings()
+	m.State = saveGameState()
+	m.Statistics = stats
+	var data []byte
+	var err error
+	data, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(userFilePath(SaveFilename), data, 0644)
+}
+
+// LoadMatch loads the saved match, and checks the rules make sense. The game
+// state can only be checked once the rules are being used, so that is done
+// by checkSavedState.
+func loadMatch() (savedMatch, error) {
+	var m savedMatch
+	var data []byte
+	var err error
+	data, err = os.ReadFile(userFilePath(SaveFilename))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return m, err
+	}
+	if m.Version != SaveFileVersion {
+		return m, errors.New("the match was saved by a different version of pong")
+	}
+	var s netSettings
+	s = m.Settings
+	err = checkNetSettings(s)
+	if err != nil {
+		return m, fmt.Errorf("the saved match doesn't make sense: %v", err)
+	}
+	var g gameState
+	g = m.State
+	if g.MyScore < 0 || g.MyScore >= s.MyTargetScore || g.ComputersScore < 0 || g.ComputersScore >= s.ComputersTargetScore {
+		return m, errors.New("the saved match has impossible scores")
+	}
+	if g.GameOver == true {
+		return m, errors.New("the saved match is already over")
+	}
+	if g.ServeRandomSeed == 0 {
+		return m, errors.New("the saved match has a broken random number generator")
+	}
+	if g.LastHitBy != NoOne && g.LastHitBy != Player && g.LastHitBy != Computer {
+		return m, errors.New("the saved match was last hit by nobody we know")
+	}
+	if g.MatchUpdates < 0 || g.FieldUpdates < 0 {
+		return m, errors.New("the saved match has a negative clock")
+	}
+	return m, nil
+}
+
+// CheckSavedState checks that a saved game state fits the rules, which must
+// already be in use. It is used for replays too.
+func checkSavedState(g gameState) error {
+	if g.MyBatY < 0 || g.MyBatY+myBatH > FieldHeight || g.ComputersBatY < 0 || g.ComputersBatY+computersBatH > FieldHeight {
+		return errors.New("a bat is off the screen")
+	}
+	if g.BallX < float64(-ballW) || g.BallX > float64(FieldWidth) || g.BallY < 0 || g.BallY > float64(FieldHeight-ballH) {
+		return errors.New("the ball is off the screen")
+	}
+	if len(g.BrokenBricks) != len(bricks) || g.BricksLeft < 0 || g.BricksLeft > len(bricks) {
+		return errors.New("the bricks are wrong")
+	}
+	return nil
+}
+
+// ContinueSavedMatch loads the saved match and carries on playing it. The
+// save file is deleted, so the same match can't be continued twice. If the
+// game is closed again the match is saved again.
+func continueSavedMatch() bool {
+	var m savedMatch
+	var err error
+	m, err = loadMatch()
+	if err == nil {
+		// remember the rules from the menu, in case they need putting back
+		var before *netSettings
+		before = makeNetSettings()
+		applyNetSettings(&m.Settings)
+		err = checkSavedState(m.State)
+		if err != nil {
+			applyNetSettings(before)
+		}
+	}
+	if err != nil {
+		fmt.Print("Failed to continue the saved match: ")
+		fmt.Println(err)
+		haveSavedMatch = false
+		return false
+	}
+	twoPlayers = false
+	playingTournamentMatch = false
+	loadGameState(m.State)
+	stats = m.Statistics
+	startMatchRecord()
+	clearRallyFrames()
+	stopInstantReplay()
+	startRecording()
+	os.Remove(userFilePath(SaveFilename))
+	haveSavedMatch = false
+	inMenu = false
+	return true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLoadMatch(t *testing.T) {
+	var tests = []struct {
+		name   string
+		change func(m *savedMatch)
+		ok     bool
+	}{
+		{"a good match", func(m *savedMatch) {}, true},
+		{"a different version", func(m *savedMatch) { m.Version = SaveFileVersion + 1 }, false},
+		{"an unknown game mode", func(m *savedMatch) { m.Settings.GameMode = 7 }, false},
+		{"an unknown arena", func(m *savedMatch) { m.Settings.ArenaNumber = len(arenas) }, false},
+		{"an unknown difficulty", func(m *savedMatch) { m.Settings.Difficulty = -1 }, false},
+		{"a negative time limit", func(m *savedMatch) { m.Settings.TimeLimit = -1 }, false},
+		{"a tiny bat", func(m *savedMatch) { m.Settings.MyBatH = MinimumBatHeight - 1 }, false},
+		{"a bat taller than the field", func(m *savedMatch) { m.Settings.ComputersBatH = FieldHeight + 1 }, false},
+		{"a bat that can't move", func(m *savedMatch) { m.Settings.ComputersBatSpeed = 0 }, false},
+		{"a ball that is too fast", func(m *savedMatch) { m.Settings.BallSpeed = MaximumBallSpeed + 1 }, false},
+		{"a target score with no digit", func(m *savedMatch) { m.Settings.MyTargetScore = HighestScore + 1 }, false},
+		{"a score that has already won", func(m *savedMatch) { m.State.MyScore = m.Settings.MyTargetScore }, false},
+		{"a negative score", func(m *savedMatch) { m.State.ComputersScore = -1 }, false},
+		{"a match that is over", func(m *savedMatch) { m.State.GameOver = true }, false},
+		{"a stuck random number generator", func(m *savedMatch) { m.State.ServeRandomSeed = 0 }, false},
+		{"an unknown player hit the ball", func(m *savedMatch) { m.State.LastHitBy = 9 }, false},
+		{"a negative clock", func(m *savedMatch) { m.State.MatchUpdates = -1 }, false},
+	}
+	var test struct {
+		name   string
+		change func(m *savedMatch)
+		ok     bool
+	}
+	for _, test = range tests {
+		setUpTestMatch(t)
+		var m savedMatch
+		m.Version = SaveFileVersion
+		m.Settings = *makeNetSettings()
+		m.State = saveGameState()
+		test.change(&m)
+		writeTestJSON(t, userFilePath(SaveFilename), m)
+		var err error
+		_, err = loadMatch()
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: loaded a match it should have turned away", test.name)
+		}
+	}
+}
+
+func TestCheckSavedState(t *testing.T) {
+	var tests = []struct {
+		name   string
+		change func(g *gameState)
+		ok     bool
+	}{
+		{"a good state", func(g *gameState) {}, true},
+		{"a bat above the field", func(g *gameState) { g.MyBatY = -1 }, false},
+		{"a bat below the field", func(g *gameState) { g.ComputersBatY = FieldHeight }, false},
+		{"a ball off the field", func(g *gameState) { g.BallX = FieldWidth * 2 }, false},
+	}
+	var test struct {
+		name   string
+		change func(g *gameState)
+		ok     bool
+	}
+	for _, test = range tests {
+		setUpTestMatch(t)
+		var g gameState
+		g = saveGameState()
+		test.change(&g)
+		var err error
+		err = checkSavedState(g)
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: accepted a state it should have turned away", test.name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	// pong.go already has a variable called image
+	goimage "image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Screenshots ----
+//
+// Press F12 to save a picture of the screen as a PNG file. The picture is
+// taken the next time the screen is drawn, just before it is shown, so it is
+// exactly what the players can see.
+
+// The folder the screenshots are saved in.
+var screenshotDirectory string
+
+// The screenshotWanted flag is true when F12 has been pressed, until the
+// screenshot has been taken.
+var screenshotWanted bool
+
+// AddScreenshotFlags adds the command line flag for screenshots.
+func addScreenshotFlags() {
+	flag.StringVar(&screenshotDirectory, "screenshot-dir", "screenshots", "the folder screenshots are saved in")
+}
+
+// HandleScreenshotEvent asks for a screenshot when F12 is pressed. It returns
+// true if it used the event.
+func handleScreenshotEvent(event sdl.Event) bool {
+	if isKeyDownEvent(event) == false {
+		return false
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	if keyDownEvt.Keysym.Sym != sdl.K_F12 {
+		return false
+	}
+	screenshotWanted = true
+	return true
+}
+
+// TakeScreenshotIfWanted saves a screenshot if F12 has been pressed. It must
+// be called after everything has been drawn, but before renderer.Present.
+func takeScreenshotIfWanted() {
+	if screenshotWanted == false {
+		return
+	}
+	screenshotWanted = false
+	var filename string
+	var err error
+	filename, err = takeScreenshot()
+	if err != nil {
+		fmt.Print("Failed to save the screenshot: ")
+		fmt.Println(err)
+		showNotice("COULD NOT SAVE THE SCREENSHOT")
+		return
+	}
+	fmt.Println("Saved a screenshot to " + filename)
+	showNotice("SAVED " + strings.ToUpper(filename))
+}
+
+// TakeScreenshot saves the picture the renderer has drawn to a PNG file
+// named after the date and time. The time includes the thousandths of a
+// second, so pressing F12 twice quickly makes two files.
+func takeScreenshot() (string, error) {
+	var filename string
+	filename = filepath.Join(screenshotDirectory, time.Now().Format("pong-2006-01-02-150405.000")+".png")
+	var err error
+	err = os.MkdirAll(screenshotDirectory, 0755)
+	if err != nil {
+		return filename, err
+	}
+	var picture *goimage.RGBA
+	picture = readRenderer()
+	if picture == nil {
+		return filename, fmt.Errorf("could not read the screen")
+	}
+	var encoder png.Encoder
+	err = savePng(&encoder, filename, picture)
+	return filename, err
+}
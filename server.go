@@ -0,0 +1,503 @@
+package main
+
+import (
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---- The dedicated server ----
+//
+// Normally one of the players' computers hosts the game. The dedicated server
+// is a copy of the game that hosts lots of matches at once, without opening
+// a window. A club can run it on one computer and every other computer can
+// play on it:
+//
+//	pong server
+//
+// Each match happens in a lobby, which has a name. The first player to join
+// a lobby plays on the left and the second plays on the right. Anyone else
+// can watch.
+//
+// The game keeps the state of the match in global variables, so it can only
+// play one match at a time. The server gets around this by keeping a copy
+// of the game state for every lobby. Every frame it loads each lobby's game
+// state in turn, updates it and saves it again.
+
+// A lobby with nobody playing in it is closed after this long.
+const LobbyAbandonedAfter = 60 * time.Second
+
+// The longest name a lobby can have, so it fits on the screen.
+const MaxLobbyNameLength = 20
+
+// The most lobbies the server has open at once, unless -max-lobbies says
+// otherwise. Every lobby is a match the server has to run every frame.
+const DefaultMaxLobbies = 50
+
+// The size of the bats and the ball when there are no pictures to measure,
+// on the server and with vector graphics (see vector.go). They are the sizes
+// of bat.png and ball.png, so every computer plays the same game.
+const PlainBatW = 25
+const PlainBatH = 76
+const PlainBallSize = 18
+
+// A lobby is one match on the server.
+type lobby struct {
+	name string
+	// players[0] plays on the left and players[1] plays on the right
+	players    [2]*netConnection
+	spectators []*netConnection
+	state      gameState
+	// when the last player left, or the zero time if there are players
+	emptySince time.Time
+}
+
+// A lobbyInfo describes a lobby for the list of lobbies.
+type lobbyInfo struct {
+	Name       string
+	Players    int
+	Spectators int
+	GameOver   bool
+}
+
+// The TCP port the server listens on.
+var serverPort int
+
+// The most lobbies the server has open at once.
+var maxLobbies int
+
+// The lobbies on the server, in the order they were created.
+var lobbies []*lobby
+
+// The computers that have connected to the server but have not said what
+// they want yet.
+var serverPendingConnections []*netConnection
+
+// The lobby a player wants to join on a server, and whether they want to
+// create it. These are set on the command line.
+var lobbyName string
+var createLobby bool
+
+// The server to list the lobbies on, from the command line.
+var listLobbiesFrom string
+
+// ParseServerCommandLine reads the settings typed after pong server. The
+// server doesn't draw, record or load anything, so it has its own few
+// settings instead of the game's, and its matches are played with the normal
+// rules.
+func parseServerCommandLine(args []string) {
+	var flags *flag.FlagSet
+	flags = flag.NewFlagSet("pong server", flag.ExitOnError)
+	flags.IntVar(&serverPort, "port", GamePort, "the TCP port the server listens on")
+	flags.IntVar(&maxLobbies, "max-lobbies", DefaultMaxLobbies, "the most lobbies the server has open at once")
+	flags.Parse(args)
+
+	var err error
+	if serverPort < 1 || serverPort > 65535 {
+		err = badSetting("port", "the port must be between 1 and 65535")
+	} else if maxLobbies < 1 {
+		err = badSetting("max-lobbies", "the server must allow at least one lobby")
+	}
+	if err != nil {
+		fmt.Print("Bad setting: ")
+		fmt.Println(err)
+		fmt.Println("Type pong server -help to see all the settings.")
+		os.Exit(2)
+	}
+	useServerRules()
+}
+
+// UseServerRules sets the rules of the matches on the server to the game's
+// normal rules.
+func useServerRules() {
+	gameMode = ClassicMode
+	arenaNumber = 0
+	timeLimit = 0
+	difficulty = NormalDifficulty
+	ballSpeed = DefaultBallSpeed
+	computerSpeed = DefaultComputerSpeed
+	winningScore = HighestScore
+	myBatHeight = 0
+	computersBatHeight = 0
+	// the bats move at the normal speed
+	myBatSpeed = 100
+	computersBatSpeed = 100
+	myStartingScore = 0
+	computersStartingScore = 0
+	myTargetScore = winningScore
+	computersTargetScore = winningScore
+}
+
+// AddLobbyFlags adds the command line flags for playing on a server.
+func addLobbyFlags() {
+	flag.StringVar(&lobbyName, "lobby", "", "the lobby to join when the game you join or watch is on a server")
+	flag.BoolVar(&createLobby, "create", false, "create the lobby instead of joining one that already exists")
+	flag.StringVar(&listLobbiesFrom, "lobbies", "", "list the lobbies on the server at this address, and quit")
+}
+
+// RunServer runs the dedicated server. It never returns.
+func runServer() {
+	initialiseServer()
+	var l net.Listener
+	var err error
+	l, err = net.Listen("tcp", ":"+strconv.Itoa(serverPort))
+	if err != nil {
+		fmt.Print("Failed to start the server: ")
+		fmt.Println(err)
+		panic(err)
+	}
+	newConnections = make(chan net.Conn)
+	go acceptConnections(l, newConnections)
+	fmt.Println("Pong server listening on port", serverPort)
+
+	var ticker *time.Ticker
+	ticker = time.NewTicker(time.Second / UpdatesPerSecond)
+	for range ticker.C {
+		serverTick()
+	}
+}
+
+// InitialiseServer sets up the sizes of everything without any graphics.
+func initialiseServer() {
+	windowWidth = FieldWidth
+	windowHeight = FieldHeight
+	// the server doesn't draw anything, so it doesn't need any effects
+	effects = false
+	// the server moves both bats for the players, so the computer must
+	// never move them itself
+	networkRole = Hosting
+	setPlainSizes()
+}
+
+// SetPlainSizes sets the size of the bats and the ball without measuring the
+// pictures.
+func setPlainSizes() {
+	myBatW = PlainBatW
+	myBatH = PlainBatH
+	// a handicap can make the bat taller or shorter than normal
+	if myBatHeight > 0 {
+		myBatH = myBatHeight
+	}
+	computersBatW = PlainBatW
+	computersBatH = PlainBatH
+	if computersBatHeight > 0 {
+		computersBatH = computersBatHeight
+	}
+	ballW = PlainBallSize
+	ballH = PlainBallSize
+}
+
+// ServerTick runs one frame of every match on the server.
+func serverTick() {
+	select {
+	case conn := <-newConnections:
+		serverPendingConnections = append(serverPendingConnections, newNetConnection(conn))
+	default:
+	}
+	pollServerPendingConnections()
+	var l *lobby
+	for _, l = range lobbies {
+		runLobby(l)
+	}
+	closeAbandonedLobbies()
+}
+
+// PollServerPendingConnections waits for the computers that have just
+// connected to say what they want.
+func pollServerPendingConnections() {
+	var stillPending []*netConnection
+	var c *netConnection
+	for _, c = range serverPendingConnections {
+		select {
+		case msg, ok := <-c.incoming:
+			if !ok {
+				closeNetConnection(c)
+			} else if msg.Version != NetworkProtocolVersion {
+				rejectConnection(c, "THE SERVER IS RUNNING A DIFFERENT VERSION OF PONG")
+			} else if msg.Kind == MsgListLobbies {
+				sendMessage(c, netMessage{Kind: MsgLobbies, Lobbies: makeLobbyList()})
+				closeNetConnection(c)
+			} else if msg.Kind == MsgHello {
+				enterLobby(c, msg)
+			} else {
+				rejectConnection(c, "SAY HELLO FIRST")
+			}
+		default:
+			stillPending = append(stillPending, c)
+		}
+	}
+	serverPendingConnections = stillPending
+}
+
+// EnterLobby puts a computer that has said hello into the lobby it asked for.
+func enterLobby(c *netConnection, msg netMessage) {
+	var name string
+	name = strings.ToUpper(strings.TrimSpace(msg.Lobby))
+	if name == "" {
+		rejectConnection(c, "THIS IS A SERVER - CHOOSE A LOBBY WITH -LOBBY")
+		return
+	}
+	// a name that is too long can't be a lobby, whether we are creating it
+	// or joining it
+	if len(name) > MaxLobbyNameLength {
+		rejectConnection(c, fmt.Sprintf("A LOBBY NAME CAN'T BE LONGER THAN %d LETTERS", MaxLobbyNameLength))
+		return
+	}
+	var l *lobby
+	l = findLobby(name)
+	if msg.CreateLobby == true {
+		if l != nil {
+			rejectConnection(c, "THERE IS ALREADY A LOBBY CALLED "+name)
+			return
+		}
+		if len(lobbies) >= maxLobbies {
+			rejectConnection(c, "THE SERVER HAS TOO MANY LOBBIES - TRY AGAIN LATER")
+			return
+		}
+		l = newLobby(name)
+	} else if l == nil {
+		rejectConnection(c, "THERE IS NO LOBBY CALLED "+name+" - CREATE IT WITH -CREATE")
+		return
+	}
+
+	if msg.Spectator == true {
+		l.spectators = append(l.spectators, c)
+		sendMessage(c, netMessage{Kind: MsgSettings, Settings: makeNetSettings()})
+		return
+	}
+	var seat int
+	seat = -1
+	if l.players[0] == nil {
+		seat = 0
+	} else if l.players[1] == nil {
+		seat = 1
+	}
+	if seat == -1 {
+		rejectConnection(c, "THE GAME IS FULL - YOU CAN WATCH IT INSTEAD")
+		return
+	}
+	l.players[seat] = c
+	l.emptySince = time.Time{}
+	sendMessage(c, netMessage{Kind: MsgSettings, Settings: makeNetSettings()})
+	fmt.Println("A player joined lobby", l.name)
+	// once both players are here the match can start
+	if l.players[0] != nil && l.players[1] != nil {
+		l.state.Paused = false
+	}
+}
+
+// FindLobby finds the lobby with a name, or returns nil if there isn't one.
+func findLobby(name string) *lobby {
+	var l *lobby
+	for _, l = range lobbies {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// NewLobby creates a lobby with a new match in it. The match is paused
+// until both players have joined.
+func newLobby(name string) *lobby {
+	var l lobby
+	l.name = name
+	l.emptySince = time.Now()
+	resetMatch()
+	paused = true
+	l.state = saveGameState()
+	lobbies = append(lobbies, &l)
+	fmt.Println("Created lobby", name)
+	return &l
+}
+
+// RunLobby runs one frame of the match in a lobby.
+func runLobby(l *lobby) {
+	loadGameState(l.state)
+	// the spectator code works on the global list of spectators, so we swap
+	// the lobby's spectators in and out just like the game state
+	spectators = l.spectators
+	pollSpectators()
+
+	var seat int
+	for seat = 0; seat < len(l.players); seat++ {
+		pollLobbyPlayer(l, seat)
+	}
+	waitingForPlayer = l.players[0] == nil || l.players[1] == nil
+	if waitingForPlayer == false && paused == false {
+		updateState()
+	}
+
+	var state *netState
+	state = makeNetState()
+	for seat = 0; seat < len(l.players); seat++ {
+		if l.players[seat] != nil {
+			sendMessage(l.players[seat], netMessage{Kind: MsgState, State: state, Spectators: spectatorCount})
+		}
+	}
+	sendStateToSpectators()
+
+	l.spectators = spectators
+	l.state = saveGameState()
+}
+
+// PollLobbyPlayer reads the messages from one of the players in a lobby.
+func pollLobbyPlayer(l *lobby, seat int) {
+	if l.players[seat] == nil {
+		return
+	}
+	for {
+		select {
+		case msg, ok := <-l.players[seat].incoming:
+			if !ok {
+				// the player has gone. Pause the match in case they come back.
+				closeNetConnection(l.players[seat])
+				l.players[seat] = nil
+				paused = true
+				if l.players[0] == nil && l.players[1] == nil {
+					l.emptySince = time.Now()
+				}
+				fmt.Println("A player left lobby", l.name)
+				return
+			}
+			if msg.Kind == MsgInput {
+				waitingForPlayer = l.players[0] == nil || l.players[1] == nil
+				handleLobbyInput(seat, msg.Input)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// HandleLobbyInput moves a player's bat. When the match is over, pause
+// starts a rematch.
+func handleLobbyInput(seat int, input int) {
+	if waitingForPlayer == true {
+		return
+	}
+	if gameOver == true {
+		if input == InputPause {
+			resetMatch()
+		}
+		return
+	}
+	if input == InputPause {
+		paused = !paused
+		return
+	}
+	if paused == true {
+		return
+	}
+	var y, h, step int
+	if seat == 0 {
+		y, h, step = myBatY, myBatH, myBatStep()
+	} else {
+		y, h, step = computersBatY, computersBatH, computersBatStep()
+	}
+	if input == InputUp {
+		y = y - step
+		if y < 0 {
+			y = 0
+		}
+	} else if input == InputDown {
+		y = y + step
+		if y+h > windowHeight {
+			y = windowHeight - h
+		}
+	}
+	if seat == 0 {
+		myBatY = y
+	} else {
+		computersBatY = y
+	}
+}
+
+// CloseAbandonedLobbies closes the lobbies nobody has played in for a while.
+func closeAbandonedLobbies() {
+	var kept []*lobby
+	var l *lobby
+	for _, l = range lobbies {
+		if l.emptySince.IsZero() == false && time.Since(l.emptySince) > LobbyAbandonedAfter {
+			var c *netConnection
+			for _, c = range l.spectators {
+				closeNetConnection(c)
+			}
+			fmt.Println("Closed abandoned lobby", l.name)
+		} else {
+			kept = append(kept, l)
+		}
+	}
+	lobbies = kept
+}
+
+// MakeLobbyList describes every lobby on the server.
+func makeLobbyList() []lobbyInfo {
+	var list []lobbyInfo
+	var l *lobby
+	for _, l = range lobbies {
+		var info lobbyInfo
+		info.Name = l.name
+		if l.players[0] != nil {
+			info.Players = info.Players + 1
+		}
+		if l.players[1] != nil {
+			info.Players = info.Players + 1
+		}
+		info.Spectators = len(l.spectators)
+		info.GameOver = l.state.GameOver
+		list = append(list, info)
+	}
+	return list
+}
+
+// ---- Talking to a server ----
+
+// ListLobbies asks a server for its lobbies and prints them.
+func listLobbies(address string) {
+	var conn net.Conn
+	var err error
+	conn, err = net.DialTimeout("tcp", withDefaultPort(address), NetworkTimeout)
+	if err != nil {
+		fmt.Print("Failed to connect to the server: ")
+		fmt.Println(err)
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(NetworkTimeout))
+	err = gob.NewEncoder(conn).Encode(&netMessage{Kind: MsgListLobbies, Version: NetworkProtocolVersion})
+	var reply netMessage
+	if err == nil {
+		err = gob.NewDecoder(conn).Decode(&reply)
+	}
+	if err != nil {
+		fmt.Print("Failed to list the lobbies: ")
+		fmt.Println(err)
+		return
+	}
+	if reply.Kind == MsgRejected {
+		fmt.Println(reply.Reason)
+		return
+	}
+	if len(reply.Lobbies) == 0 {
+		fmt.Println("There are no lobbies. Create one with: pong -join", address, "-lobby NAME -create")
+		return
+	}
+	var info lobbyInfo
+	for _, info = range reply.Lobbies {
+		var status string
+		if info.GameOver == true {
+			status = "game over"
+		} else if info.Players < 2 {
+			status = "waiting for a player"
+		} else {
+			status = "playing"
+		}
+		fmt.Printf("%-20s %d/2 players, %d watching, %s\n", info.Name, info.Players, info.Spectators, status)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+// TestEnterLobby checks the server puts players into the lobbies they ask
+// for, and tells them why when it can't.
+func TestEnterLobby(t *testing.T) {
+	// the tests are in order, because each one can change the lobbies
+	var tests = []struct {
+		name   string
+		lobby  string
+		create bool
+		reason string
+	}{
+		{"no lobby", "", false, "CHOOSE A LOBBY"},
+		{"creating a long name", "the lobby with the longest name", true, "LONGER THAN"},
+		{"joining a long name", "the lobby with the longest name", false, "LONGER THAN"},
+		{"joining a lobby that isn't there", "final", false, "NO LOBBY CALLED FINAL"},
+		{"creating a lobby", "final", true, ""},
+		{"creating it again", "Final", true, "ALREADY A LOBBY"},
+		{"creating too many lobbies", "semi final", true, "TOO MANY LOBBIES"},
+		{"joining a lobby", " final ", false, ""},
+		{"joining a full lobby", "final", false, "THE GAME IS FULL"},
+	}
+	setUpTestMatch(t)
+	lobbies = nil
+	maxLobbies = 1
+	t.Cleanup(func() { lobbies = nil })
+	var test struct {
+		name   string
+		lobby  string
+		create bool
+		reason string
+	}
+	for _, test = range tests {
+		var serverEnd, playerEnd net.Conn
+		serverEnd, playerEnd = net.Pipe()
+		var player *netConnection
+		player = newNetConnection(playerEnd)
+		enterLobby(newNetConnection(serverEnd), netMessage{Kind: MsgHello, Version: NetworkProtocolVersion, Lobby: test.lobby, CreateLobby: test.create})
+		var msg netMessage
+		if test.reason == "" {
+			msg = waitForMessage(t, player, MsgSettings)
+		} else {
+			msg = waitForMessage(t, player, MsgRejected)
+			if strings.Contains(msg.Reason, test.reason) == false {
+				t.Errorf("%s: the player was told %q", test.name, msg.Reason)
+			}
+		}
+		closeNetConnection(player)
+	}
+	if len(lobbies) != 1 || lobbies[0].players[0] == nil || lobbies[0].players[1] == nil {
+		t.Error("the players aren't both in the lobby")
+	}
+}
+
+// TestLobbyRematch checks a rematch in a lobby starts the lobby's match
+// again without touching anything else.
+func TestLobbyRematch(t *testing.T) {
+	setUpTestMatch(t)
+	myScore = 3
+	computersScore = HighestScore
+	gameOver = true
+	waitingForPlayer = false
+	resultRecorded = true
+	handleLobbyInput(0, InputPause)
+	if gameOver == true || myScore != myStartingScore || computersScore != computersStartingScore {
+		t.Errorf("the rematch didn't start: game over is %v and the score is %d-%d", gameOver, myScore, computersScore)
+	}
+	if resultRecorded == false {
+		t.Error("the rematch started a new match record")
+	}
+}
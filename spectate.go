@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strconv"
+)
+
+// ---- Watching a network game ----
+//
+// As well as the player who joins the game, any number of other computers
+// can connect to the host to watch. They are called spectators. A spectator
+// is sent the same messages as a joining player who is not using rollback:
+// the settings for the match, and then where everything is, every frame.
+// A spectator cannot move anything, so nothing it presses is sent to the host.
+//
+// A spectator draws the game a little behind the players. The delay smooths
+// over messages that arrive late, so the ball moves smoothly on the
+// projector even if the network is busy.
+
+// How many frames behind the players a spectator draws the game.
+const SpectatorDelayFrames = 30
+
+// The computers watching the game we are hosting. Web browsers can watch too,
+// see web.go.
+var spectators []*netConnection
+
+// The number of spectators watching the game we are playing or watching.
+// The host tells the other computers how many there are.
+var spectatorCount int
+
+// The game states a spectator has received but not drawn yet, oldest first.
+var delayedStates []*netState
+
+// ---- The host ----
+
+// AddSpectator lets a computer watch the game, and sends it the settings
+// for the match.
+func addSpectator(c *netConnection) {
+	spectators = append(spectators, c)
+	spectatorCount = countSpectators()
+	sendMessage(c, netMessage{Kind: MsgSettings, Settings: makeNetSettings()})
+}
+
+// PollSpectators reads the messages from the spectators. They only ever send
+// pings, but reading them tells us which spectators have gone away.
+func pollSpectators() {
+	var stillWatching []*netConnection
+	var c *netConnection
+	for _, c = range spectators {
+		var lost bool
+		lost = false
+	readMessages:
+		for {
+			select {
+			case _, ok := <-c.incoming:
+				if !ok {
+					lost = true
+					break readMessages
+				}
+			default:
+				break readMessages
+			}
+		}
+		if lost == true {
+			closeNetConnection(c)
+		} else {
+			stillWatching = append(stillWatching, c)
+		}
+	}
+	spectators = stillWatching
+	spectatorCount = countSpectators()
+}
+
+// CountSpectators counts the computers and the web browsers watching the
+// game we are hosting.
+func countSpectators() int {
+	return len(spectators) + len(webSpectators)
+}
+
+// SendStateToSpectators tells every spectator where everything is.
+func sendStateToSpectators() {
+	if len(spectators) == 0 {
+		return
+	}
+	var state *netState
+	state = makeNetState()
+	var c *netConnection
+	for _, c = range spectators {
+		sendMessage(c, netMessage{Kind: MsgState, State: state, Spectators: spectatorCount})
+	}
+}
+
+// CloseSpectators disconnects all of the spectators.
+func closeSpectators() {
+	var c *netConnection
+	for _, c = range spectators {
+		closeNetConnection(c)
+	}
+	spectators = nil
+	spectatorCount = 0
+}
+
+// ---- The spectator ----
+
+// WatchGame connects to a host to watch the game.
+func watchGame(address string) error {
+	return connectToHost(address, true)
+}
+
+// DelayState keeps a game state from the host until it is time to draw it.
+func delayState(s *netState) {
+	delayedStates = append(delayedStates, s)
+	// if the states have piled up, for example because the network stopped
+	// for a moment, skip ahead rather than falling further and further behind
+	if len(delayedStates) > 2*SpectatorDelayFrames {
+		delayedStates = delayedStates[len(delayedStates)-SpectatorDelayFrames:]
+	}
+}
+
+// ApplyDelayedState draws the oldest game state, once we have enough of them
+// saved up.
+func applyDelayedState() {
+	if len(delayedStates) < SpectatorDelayFrames {
+		return
+	}
+	applyNetState(delayedStates[0])
+	delayedStates = delayedStates[1:]
+}
+
+// RenderSpectatorCount shows how many people are watching. A spectator is
+// also told that it is only watching.
+func renderSpectatorCount() {
+	var text string
+	if networkRole == Spectating {
+		text = "WATCHING - SPECTATORS: " + strconv.Itoa(spectatorCount)
+	} else if networkRole != NotNetworked && spectatorCount > 0 {
+		text = "SPECTATORS: " + strconv.Itoa(spectatorCount)
+	} else {
+		return
+	}
+	renderTextCentred(text, windowWidth/2, windowHeight-24, 2, 128, 128, 128)
+}
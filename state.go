@@ -0,0 +1,114 @@
+package main
+
+// ---- The game state ----
+//
+// The game state is every variable that changes while the game is being
+// played: where the bats and the ball are, the scores, the clock and so on.
+// If we save the game state we can put everything back exactly how it was
+// later, just by loading it again.
+//
+// The game is deterministic. That means that if we start from the same game
+// state and the players press the same keys, the game will always play out
+// in exactly the same way. Even the direction the ball is served in is
+// decided by our own random number generator, whose state is part of the
+// game state.
+
+// A gameState holds a copy of all of the game state variables.
+// The names start with capital letters so the gob package can send a game
+// state over the network or save it to a file.
+type gameState struct {
+	MyBatY          int
+	ComputersBatY   int
+	BallX           float64
+	BallY           float64
+	BallDirX        float64
+	BallDirY        float64
+	MyScore         int
+	ComputersScore  int
+	Paused          bool
+	GameOver        bool
+	LastHitBy       int
+	MatchUpdates    int
+	SuddenDeath     bool
+	FieldUpdates    int
+	BrokenBricks    []bool
+	BricksLeft      int
+	ServeRandomSeed uint64
+}
+
+// The state of the random number generator that decides which way the ball
+// is served. It must never be zero.
+var serveRandomSeed uint64
+
+// SaveGameState makes a copy of the game state.
+func saveGameState() gameState {
+	var s gameState
+	s.MyBatY = myBatY
+	s.ComputersBatY = computersBatY
+	s.BallX = ballX
+	s.BallY = ballY
+	s.BallDirX = ballDirX
+	s.BallDirY = ballDirY
+	s.MyScore = myScore
+	s.ComputersScore = computersScore
+	s.Paused = paused
+	s.GameOver = gameOver
+	s.LastHitBy = lastHitBy
+	s.MatchUpdates = matchUpdates
+	s.SuddenDeath = suddenDeath
+	s.FieldUpdates = fieldUpdates
+	s.BricksLeft = bricksLeft
+	s.ServeRandomSeed = serveRandomSeed
+	// we must copy the bricks one by one. If we just copied the slice, the
+	// copy would share its bricks with the game.
+	s.BrokenBricks = make([]bool, len(bricks))
+	var i int
+	for i = 0; i < len(bricks); i++ {
+		s.BrokenBricks[i] = bricks[i].broken
+	}
+	return s
+}
+
+// LoadGameState puts the game state back to a copy made by saveGameState.
+func loadGameState(s gameState) {
+	myBatY = s.MyBatY
+	computersBatY = s.ComputersBatY
+	ballX = s.BallX
+	ballY = s.BallY
+	ballDirX = s.BallDirX
+	ballDirY = s.BallDirY
+	myScore = s.MyScore
+	computersScore = s.ComputersScore
+	paused = s.Paused
+	gameOver = s.GameOver
+	lastHitBy = s.LastHitBy
+	matchUpdates = s.MatchUpdates
+	suddenDeath = s.SuddenDeath
+	fieldUpdates = s.FieldUpdates
+	bricksLeft = s.BricksLeft
+	serveRandomSeed = s.ServeRandomSeed
+	var i int
+	for i = 0; i < len(bricks) && i < len(s.BrokenBricks); i++ {
+		bricks[i].broken = s.BrokenBricks[i]
+	}
+}
+
+// SeedServeRandom starts the serve random number generator from a seed.
+// Two games started from the same seed serve the ball in the same directions.
+func seedServeRandom(seed uint64) {
+	// the generator gets stuck if its state is ever zero
+	if seed == 0 {
+		seed = 1
+	}
+	serveRandomSeed = seed
+}
+
+// ServeRandomNumber picks a random number between min and max (inclusive).
+// It uses a very simple random number generator called xorshift. It shuffles
+// the bits of the state around to make the next number.
+func serveRandomNumber(min, max int) int {
+	serveRandomSeed = serveRandomSeed ^ (serveRandomSeed << 13)
+	serveRandomSeed = serveRandomSeed ^ (serveRandomSeed >> 7)
+	serveRandomSeed = serveRandomSeed ^ (serveRandomSeed << 17)
+	return min + int(serveRandomSeed%uint64(max-min+1))
+}
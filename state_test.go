@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestGameState plays a match on from a saved game state twice, and checks
+// it turns out the same both times.
+func TestGameState(t *testing.T) {
+	setUpTestMatch(t)
+	// the computer must not move the right bat, because the AI isn't part
+	// of the game state
+	networkRole = Hosting
+	var start gameState
+	start = saveGameState()
+	var i int
+	for i = 0; i < 10*UpdatesPerSecond; i++ {
+		updateState()
+	}
+	var first gameState
+	first = saveGameState()
+	if reflect.DeepEqual(start, first) == true {
+		t.Fatal("nothing happened")
+	}
+	loadGameState(start)
+	if reflect.DeepEqual(saveGameState(), start) == false {
+		t.Fatal("loading the game state didn't put everything back")
+	}
+	for i = 0; i < 10*UpdatesPerSecond; i++ {
+		updateState()
+	}
+	if reflect.DeepEqual(saveGameState(), first) == false {
+		t.Errorf("the match played out differently the second time\n%+v\n%+v", first, saveGameState())
+	}
+}
+
+func TestServeRandomNumber(t *testing.T) {
+	var numbers [2][]int
+	var game, i int
+	for game = 0; game < 2; game++ {
+		seedServeRandom(12345)
+		for i = 0; i < 100; i++ {
+			numbers[game] = append(numbers[game], serveRandomNumber(-3, 3))
+		}
+	}
+	if reflect.DeepEqual(numbers[0], numbers[1]) == false {
+		t.Error("the same seed gave different numbers")
+	}
+	var seen [7]bool
+	var n int
+	for _, n = range numbers[0] {
+		if n < -3 || n > 3 {
+			t.Fatalf("%d is not between -3 and 3", n)
+		}
+		seen[n+3] = true
+	}
+	for i = 0; i < len(seen); i++ {
+		if seen[i] == false {
+			t.Errorf("%d never came up", i-3)
+		}
+	}
+	// a seed of zero would get stuck at zero for ever
+	seedServeRandom(0)
+	serveRandomNumber(0, 1)
+	if serveRandomSeed == 0 {
+		t.Error("the generator got stuck")
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Match statistics ----
+//
+// While a match is being played the game counts how it is going: how many
+// times each player hits the ball, whether they hit it with the middle or
+// the edge of the bat, how long the rallies are, how fast the ball goes and
+// how many points each player wins when they serve. When the match is over
+// the statistics are shown on the match summary screen.
+//
+// The player who serves is the one the ball moves away from at the start of
+// a rally.
+
+// Where the ball hits the bat is measured from -2 at the top edge, through 0
+// in the middle, to 2 at the bottom edge (see reflectBallFromPlayersBat). A
+// hit further from the middle than EdgeHit is an edge hit. 1 means the top
+// and bottom quarters of the bat are the edges.
+const EdgeHit = 1.0
+
+// A playerStatistics holds the statistics for one player. The names start
+// with capital letters so they can be saved.
+type playerStatistics struct {
+	Hits             int
+	CentreHits       int
+	EdgeHits         int
+	Points           int
+	Serves           int
+	PointsWonOnServe int
+}
+
+// A matchStatistics holds the statistics for the whole match.
+type matchStatistics struct {
+	Left  playerStatistics
+	Right playerStatistics
+	// the number of rallies that have finished, and how many hits there
+	// were in them altogether
+	Rallies   int
+	RallyHits int
+	// the number of hits in the rally being played
+	CurrentRally int
+	LongestRally int
+	// the fastest the ball has moved, in pixels a second
+	FastestBall float64
+	// how many frames the match has lasted
+	Frames int
+	// who served the rally being played
+	Server int
+}
+
+// The statistics for the match being played.
+var stats matchStatistics
+
+// StartMatchStatistics starts counting a new match. The ball must already
+// have been served.
+func startMatchStatistics() {
+	stats = matchStatistics{}
+	noteServe()
+}
+
+// CountingStatistics is true if what happens now should be counted. Rollback
+// plays frames again when it guesses wrong (see rollback.go), and those
+// frames have already been counted.
+func countingStatistics() bool {
+	return resimulating == false
+}
+
+// StatisticsFor finds the statistics for the Player or the Computer.
+func statisticsFor(who int) *playerStatistics {
+	if who == Player {
+		return &stats.Left
+	}
+	return &stats.Right
+}
+
+// NoteServe remembers who served. It is called every time the ball is served.
+func noteServe() {
+	if countingStatistics() == false {
+		return
+	}
+	if ballDirX < 0 {
+		// the ball is going towards me, so the computer served
+		stats.Server = Computer
+	} else {
+		stats.Server = Player
+	}
+}
+
+// CountMatchFrame adds one frame to the length of the match. It is called
+// every time the game state is updated.
+func countMatchFrame() {
+	if countingStatistics() == false {
+		return
+	}
+	stats.Frames = stats.Frames + 1
+}
+
+// CountBatHit counts a hit by the Player or the Computer. Reflection is where
+// on the bat the ball hit, from -2 to 2.
+func countBatHit(who int, reflection float64) {
+	if countingStatistics() == false {
+		return
+	}
+	var p *playerStatistics
+	p = statisticsFor(who)
+	p.Hits = p.Hits + 1
+	if math.Abs(reflection) > EdgeHit {
+		p.EdgeHits = p.EdgeHits + 1
+	} else {
+		p.CentreHits = p.CentreHits + 1
+	}
+	stats.CurrentRally = stats.CurrentRally + 1
+	if stats.CurrentRally > stats.LongestRally {
+		stats.LongestRally = stats.CurrentRally
+	}
+}
+
+// NotePoint counts a point won by the Player or the Computer, which ends the
+// rally.
+func notePoint(winner int) {
+	if countingStatistics() == false {
+		return
+	}
+	stats.Rallies = stats.Rallies + 1
+	stats.RallyHits = stats.RallyHits + stats.CurrentRally
+	stats.CurrentRally = 0
+	var p *playerStatistics
+	p = statisticsFor(winner)
+	p.Points = p.Points + 1
+	var server *playerStatistics
+	server = statisticsFor(stats.Server)
+	server.Serves = server.Serves + 1
+	if winner == stats.Server {
+		server.PointsWonOnServe = server.PointsWonOnServe + 1
+	}
+}
+
+// NoteBallSpeed remembers the fastest the ball has moved. XDelta and yDelta
+// are how far it moved in one frame.
+func noteBallSpeed(xDelta, yDelta float64) {
+	if countingStatistics() == false {
+		return
+	}
+	var speed float64
+	speed = math.Hypot(xDelta, yDelta) * UpdatesPerSecond
+	if speed > stats.FastestBall {
+		stats.FastestBall = speed
+	}
+}
+
+// ---- The match summary screen ----
+
+// ShowingMatchSummary is true when the match summary should be on the
+// screen. It is shown when a match played on this computer is over, after any
+// instant replay of the last point.
+func showingMatchSummary() bool {
+	return gameOver == true && showingInstantReplay == false &&
+		networkRole == NotNetworked && playingBack == false
+}
+
+// HandleMatchSummaryEvent goes back to the menu when return is pressed on the
+// match summary. In a tournament the tournament decides what return does. It
+// returns true if it used the event.
+func handleMatchSummaryEvent(event sdl.Event) bool {
+	if showingMatchSummary() == false || playingTournamentMatch == true {
+		return false
+	}
+	if isKeyDownEvent(event) == false {
+		return false
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	if keyDownEvt.Keysym.Sym != sdl.K_RETURN {
+		return false
+	}
+	inMenu = true
+	return true
+}
+
+// RenderMatchSummary draws the match summary screen.
+func renderMatchSummary() {
+	var left, right string
+	left, right = matchPlayerNames()
+	var winner string
+	if myScore > computersScore {
+		winner = left + " WINS "
+	} else if computersScore > myScore {
+		winner = right + " WINS "
+	} else {
+		winner = "DRAW "
+	}
+	renderTextCentred("MATCH SUMMARY", windowWidth/2, 40, 6, 255, 255, 255)
+	renderTextCentred(winner+strconv.Itoa(myScore)+"-"+strconv.Itoa(computersScore),
+		windowWidth/2, 110, 4, 255, 255, 0)
+
+	// a table with a column for each player
+	var labelX, leftX, rightX, y int
+	labelX = windowWidth/2 - 320
+	leftX = windowWidth/2 + 40
+	rightX = windowWidth/2 + 220
+	y = 190
+	renderText(left, leftX, y+6, 2, 255, 255, 0)
+	renderText(right, rightX, y+6, 2, 255, 255, 0)
+	renderSummaryRow("POINTS", stats.Left.Points, stats.Right.Points, labelX, leftX, rightX, y+50)
+	renderSummaryRow("HITS", stats.Left.Hits, stats.Right.Hits, labelX, leftX, rightX, y+90)
+	renderSummaryRow("CENTRE HITS", stats.Left.CentreHits, stats.Right.CentreHits, labelX, leftX, rightX, y+130)
+	renderSummaryRow("EDGE HITS", stats.Left.EdgeHits, stats.Right.EdgeHits, labelX, leftX, rightX, y+170)
+	renderText("POINTS WON ON SERVE", labelX, y+210, 3, 255, 255, 255)
+	renderText(strconv.Itoa(stats.Left.PointsWonOnServe)+"/"+strconv.Itoa(stats.Left.Serves), leftX, y+210, 3, 255, 255, 255)
+	renderText(strconv.Itoa(stats.Right.PointsWonOnServe)+"/"+strconv.Itoa(stats.Right.Serves), rightX, y+210, 3, 255, 255, 255)
+
+	// then the statistics for the whole match
+	var average string
+	average = "-"
+	if stats.Rallies > 0 {
+		average = strconv.FormatFloat(float64(stats.RallyHits)/float64(stats.Rallies), 'f', 1, 64)
+	}
+	var played time.Duration
+	played = time.Duration(stats.Frames) * time.Second / UpdatesPerSecond
+	y = y + 280
+	renderText("LONGEST RALLY: "+strconv.Itoa(stats.LongestRally)+" HITS", labelX, y, 3, 255, 255, 255)
+	renderText("AVERAGE RALLY: "+average+" HITS", labelX, y+40, 3, 255, 255, 255)
+	renderText("FASTEST BALL: "+strconv.Itoa(int(stats.FastestBall))+" PIXELS A SECOND", labelX, y+80, 3, 255, 255, 255)
+	renderText("MATCH TIME: "+formatClock(played), labelX, y+120, 3, 255, 255, 255)
+
+	renderTextCentred("RETURN TO CONTINUE", windowWidth/2, windowHeight-40, 2, 128, 128, 128)
+}
+
+// RenderSummaryRow draws one row of the table on the match summary screen.
+func renderSummaryRow(label string, left, right int, labelX, leftX, rightX, y int) {
+	renderText(label, labelX, y, 3, 255, 255, 255)
+	renderText(strconv.Itoa(left), leftX, y, 3, 255, 255, 255)
+	renderText(strconv.Itoa(right), rightX, y, 3, 255, 255, 255)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Text ----
+//
+// We only have graphics for the numbers 0 to 11, so to write words on the
+// screen we draw each letter ourselves out of small squares. This is how the
+// very first computer games drew their text.
+//
+// Each letter is 5 squares wide and 7 squares tall. A # means we draw a square
+// and a . means we leave a gap.
+
+// The width and height of a letter, counted in squares
+const LetterWidth = 5
+const LetterHeight = 7
+
+// The shapes of all of the letters we know how to draw.
+// A map lets us look up the shape of a letter using the letter itself.
+var letterShapes = map[rune][LetterHeight]string{
+	'A':  {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B':  {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C':  {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D':  {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E':  {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F':  {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G':  {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".###."},
+	'H':  {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I':  {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J':  {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K':  {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L':  {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M':  {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N':  {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O':  {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P':  {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q':  {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R':  {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S':  {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T':  {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U':  {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V':  {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W':  {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X':  {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y':  {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z':  {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0':  {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1':  {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2':  {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3':  {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4':  {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5':  {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6':  {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7':  {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8':  {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9':  {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	':':  {".....", "..#..", "..#..", ".....", "..#..", "..#..", "....."},
+	'.':  {".....", ".....", ".....", ".....", ".....", ".##..", ".##.."},
+	',':  {".....", ".....", ".....", ".....", ".##..", "..#..", ".#..."},
+	'-':  {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'+':  {".....", "..#..", "..#..", "#####", "..#..", "..#..", "....."},
+	'/':  {".....", "....#", "...#.", "..#..", ".#...", "#....", "....."},
+	'!':  {"..#..", "..#..", "..#..", "..#..", "..#..", ".....", "..#.."},
+	'?':  {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+	'<':  {"...#.", "..#..", ".#...", "#....", ".#...", "..#..", "...#."},
+	'>':  {".#...", "..#..", "...#.", "....#", "...#.", "..#..", ".#..."},
+	'\'': {"..#..", "..#..", ".#...", ".....", ".....", ".....", "....."},
+	'%':  {"##...", "##..#", "...#.", "..#..", ".#...", "#..##", "...##"},
+	'_':  {".....", ".....", ".....", ".....", ".....", ".....", "#####"},
+	'(':  {"...#.", "..#..", ".#...", ".#...", ".#...", "..#..", "...#."},
+	')':  {".#...", "..#..", "...#.", "...#.", "...#.", "..#..", ".#..."},
+	'=':  {".....", ".....", "#####", ".....", "#####", ".....", "....."},
+}
+
+// TextWidth works out how wide a piece of text will be on the screen in
+// pixels. Scale is the size of each square in pixels.
+func textWidth(text string, scale int) int {
+	if len(text) == 0 {
+		return 0
+	}
+	// every letter is LetterWidth squares wide and has a one square gap after
+	// it, apart from the last letter.
+	var letters int
+	letters = len([]rune(text))
+	return (letters*(LetterWidth+1) - 1) * scale
+}
+
+// TextHeight works out how tall a line of text will be on the screen in pixels.
+func textHeight(scale int) int {
+	return LetterHeight * scale
+}
+
+// RenderText draws some text with its top left corner at x and y.
+// Scale is the size of each square in pixels, so a scale of 4 makes each
+// letter 20 pixels wide and 28 pixels tall.
+// Letters are always drawn in upper case. We don't have shapes for lower
+// case letters.
+func renderText(text string, x, y, scale int, r, g, b uint8) {
+	renderer.SetDrawColor(r, g, b, 255)
+	var letter rune
+	for _, letter = range strings.ToUpper(text) {
+		renderLetter(letter, x, y, scale)
+		x = x + (LetterWidth+1)*scale
+	}
+	// put the draw colour back to black. renderer.Clear uses the draw colour.
+	renderer.SetDrawColor(0, 0, 0, 0)
+}
+
+// RenderTextCentred draws some text so that the middle of the text is at x.
+func renderTextCentred(text string, x, y, scale int, r, g, b uint8) {
+	renderText(text, x-textWidth(text, scale)/2, y, scale, r, g, b)
+}
+
+// RenderLetter draws a single letter, one square at a time.
+// If we don't know the shape of a letter we just leave a space.
+func renderLetter(letter rune, x, y, scale int) {
+	var shape [LetterHeight]string
+	var ok bool
+	shape, ok = letterShapes[letter]
+	if !ok {
+		return
+	}
+	var row, column int
+	for row = 0; row < LetterHeight; row++ {
+		for column = 0; column < LetterWidth; column++ {
+			if shape[row][column] != '#' {
+				continue
+			}
+			var square sdl.Rect
+			square.X = int32(x + column*scale)
+			square.Y = int32(y + row*scale)
+			square.W = int32(scale)
+			square.H = int32(scale)
+			renderer.FillRect(&square)
+		}
+	}
+}
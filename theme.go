@@ -0,0 +1,654 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Themes ----
+//
+// A theme decides how the game looks: the court, each bat, the ball, the
+// scores and the game over banner. The theme can be changed on the menu.
+//
+// The court is drawn behind everything else. It is the background, the net
+// down the middle, the boundary lines along the top and the bottom, and the
+// service areas. The service areas are a line across the court near each end,
+// like on a tennis court, and a line along the middle from each of them to
+// the net.
+//
+// The court markings are only decoration. The ball still bounces off the top
+// and the bottom of the playing field, and goes out at the ends, wherever the
+// lines are drawn. The boundary lines are drawn along the top and bottom edges
+// of the playing field, so the ball runs over them before it bounces, and the
+// ball and the bats ignore the service areas.
+//
+// Each theme is a JSON file in the themes folder of the assets. Everything a
+// theme draws is a themePicture, which is either one of the pictures in the
+// assets or a simple shape, in a colour. For example
+//
+//	{
+//	  "Name": "NEON",
+//	  "Background": {"Colour": "#000018"},
+//	  "CentreLine": {"Style": "dashed", "Colour": "#ff00ff", "Width": 4},
+//	  "Boundary": {"Style": "solid", "Colour": "#ff00ff", "Width": 4},
+//	  "ServiceLines": {"Style": "solid", "Colour": "#400040", "Distance": 256},
+//	  "LeftBat": {"Shape": "rectangle", "Colour": "#00ffff"},
+//	  "RightBat": {"Image": "graphics/bat.png", "Colour": "#ff00ff"},
+//	  "Ball": {"Shape": "circle", "Colour": "#ffff00"},
+//	  "Bricks": {"Shape": "rectangle", "Colour": "#00ff80"},
+//	  "Digits": {"Shape": "font", "Colour": "#00ffff"},
+//	  "GameOver": {"Shape": "font", "Colour": "#ff00ff"}
+//	}
+//
+// The digits can be pictures too: "Image": "graphics/%d.png" loads
+// graphics/0.png to graphics/11.png.
+//
+// A theme only changes how things look. The bats and the ball are always the
+// same size as the built in graphics, so the game plays the same whatever
+// the theme is, and players with different themes can play each other.
+
+// The shapes a theme can draw instead of a picture. A font shape draws the
+// digits, or the words GAME OVER, with the squares from text.go.
+const RectangleShape = "rectangle"
+const CircleShape = "circle"
+const FontShape = "font"
+
+// The styles of the line down the middle of the playing field.
+const NoLine = "none"
+const SolidLine = "solid"
+const DashedLine = "dashed"
+
+// The length of each dash, and the gap between the dashes, in pixels.
+const DashLength = 16
+
+// A themePicture is one thing a theme draws. If Image is set the picture is
+// drawn, tinted with Colour. Otherwise, and always with vector graphics (see
+// vector.go), Shape is drawn in Colour. The names
+// start with capital letters so they can be loaded from JSON.
+type themePicture struct {
+	Image  string
+	Shape  string
+	Colour string
+	// the colour as numbers, and the pictures once they have been loaded.
+	// There are 12 pictures for the digits and one for everything else.
+	r, g, b  uint8
+	textures []*sdl.Texture
+	// the words drawn by a font shape
+	text string
+}
+
+// The tint used for a picture nobody has chosen a colour for. Tinting with
+// white leaves the colours as they are.
+var untinted = batColour{"WHITE", 255, 255, 255}
+
+// A themeLine is one of the court markings. Distance is only used by the
+// service lines, and is how far they are from each end of the court.
+type themeLine struct {
+	Style    string
+	Colour   string
+	Width    int
+	Distance int
+	r, g, b  uint8
+}
+
+// A theme is everything a theme file says.
+type theme struct {
+	Name         string
+	Background   themePicture
+	CentreLine   themeLine
+	Boundary     themeLine
+	ServiceLines themeLine
+	LeftBat      themePicture
+	RightBat     themePicture
+	Ball         themePicture
+	Bricks       themePicture
+	Digits       themePicture
+	GameOver     themePicture
+}
+
+// All the themes that were loaded, sorted by name.
+var themes []*theme
+
+// The theme being used.
+var themeNumber int
+
+// The theme chosen in the settings.
+var themeName string
+
+// AddThemeFlags adds the command line flag that chooses the theme.
+func addThemeFlags() {
+	flag.StringVar(&themeName, "theme", "classic", "how the game looks. The themes are in the themes folder of the assets")
+}
+
+// CheckThemeFlags loads the themes and finds the theme chosen in the
+// settings. The pictures aren't loaded until the theme is used.
+func checkThemeFlags() error {
+	loadThemes()
+	var i int
+	for i = 0; i < len(themes); i++ {
+		if strings.EqualFold(themes[i].Name, themeName) == true {
+			themeNumber = i
+			return nil
+		}
+	}
+	return badSetting("theme", "there is no theme called "+themeName)
+}
+
+// LoadThemes reads every theme file, from the built in assets and from the
+// assets folder. A theme file that doesn't make sense is left out.
+func loadThemes() {
+	themes = nil
+	var name string
+	for _, name = range themeFilenames() {
+		var t *theme
+		var err error
+		t, err = loadTheme(name)
+		if err != nil {
+			fmt.Print("Failed to load the theme " + name + ": ")
+			fmt.Println(err)
+			continue
+		}
+		themes = append(themes, t)
+	}
+	// the classic theme is built in, but it is here in case the theme
+	// files have all gone wrong
+	if len(themes) == 0 {
+		var classic *theme
+		classic = classicTheme()
+		finishTheme(classic)
+		themes = append(themes, classic)
+	}
+	sort.SliceStable(themes, func(i, j int) bool {
+		return themes[i].Name < themes[j].Name
+	})
+}
+
+// ThemeFilenames finds the names of all the theme files. A file in the assets
+// folder with the same name as a built in one replaces it.
+func themeFilenames() []string {
+	var names []string
+	var seen map[string]bool
+	seen = make(map[string]bool)
+	var add = func(name string) {
+		if strings.HasSuffix(name, ".json") == true && seen[name] == false {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var entries []os.DirEntry
+	var entry os.DirEntry
+	var err error
+	entries, err = builtInAssets.ReadDir("assets/themes")
+	if err == nil {
+		for _, entry = range entries {
+			add(entry.Name())
+		}
+	}
+	if assetsDirectory != "" {
+		entries, err = os.ReadDir(filepath.Join(assetsDirectory, "themes"))
+		if err == nil {
+			for _, entry = range entries {
+				add(entry.Name())
+			}
+		}
+	}
+	return names
+}
+
+// LoadTheme reads one theme file and checks it makes sense.
+func loadTheme(filename string) (*theme, error) {
+	var data []byte
+	var err error
+	data, err = readAsset("themes/" + filename)
+	if err != nil {
+		return nil, err
+	}
+	var t theme
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		return nil, err
+	}
+	err = finishTheme(&t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// FinishTheme fills in the parts of a theme that were left out, and checks
+// the rest makes sense.
+func finishTheme(t *theme) error {
+	if t.Name == "" {
+		return errors.New("the theme doesn't have a name")
+	}
+	t.Name = strings.ToUpper(t.Name)
+	// anything the theme doesn't say is drawn the classic way
+	var classic *theme
+	classic = classicTheme()
+	useClassicPicture(&t.LeftBat, classic.LeftBat)
+	useClassicPicture(&t.RightBat, classic.RightBat)
+	useClassicPicture(&t.Ball, classic.Ball)
+	useClassicPicture(&t.Bricks, classic.Bricks)
+	useClassicPicture(&t.Digits, classic.Digits)
+	useClassicPicture(&t.GameOver, classic.GameOver)
+	useClassicLine(&t.CentreLine, classic.CentreLine)
+	useClassicLine(&t.Boundary, classic.Boundary)
+	useClassicLine(&t.ServiceLines, classic.ServiceLines)
+	if t.Background.Colour == "" {
+		t.Background.Colour = "#000000"
+	}
+	if t.Background.Shape == "" {
+		t.Background.Shape = RectangleShape
+	}
+
+	t.GameOver.text = "GAME OVER"
+
+	// the shapes that make sense for each picture
+	var err error
+	var shapes []string
+	shapes = []string{RectangleShape, CircleShape}
+	err = checkThemePicture("Background", &t.Background, shapes)
+	if err == nil {
+		err = checkThemePicture("LeftBat", &t.LeftBat, shapes)
+	}
+	if err == nil {
+		err = checkThemePicture("RightBat", &t.RightBat, shapes)
+	}
+	if err == nil {
+		err = checkThemePicture("Ball", &t.Ball, shapes)
+	}
+	if err == nil {
+		// a circle wouldn't fill a brick, which is taller than it is wide
+		err = checkThemePicture("Bricks", &t.Bricks, []string{RectangleShape})
+	}
+	if err == nil {
+		err = checkThemePicture("Digits", &t.Digits, []string{FontShape})
+	}
+	if err == nil {
+		err = checkThemePicture("GameOver", &t.GameOver, []string{FontShape})
+	}
+	if err == nil {
+		err = checkThemeLine("CentreLine", &t.CentreLine)
+	}
+	if err == nil {
+		err = checkThemeLine("Boundary", &t.Boundary)
+	}
+	if err == nil {
+		err = checkThemeLine("ServiceLines", &t.ServiceLines)
+	}
+	if err != nil {
+		return err
+	}
+	// the service lines must be between the ends of the court and the net
+	if t.ServiceLines.Distance < 1 || t.ServiceLines.Distance >= FieldWidth/2 {
+		return fmt.Errorf("the ServiceLines Distance must be between 1 and %d", FieldWidth/2-1)
+	}
+	return nil
+}
+
+// UseClassicLine fills in the parts of a court marking the theme file left
+// out.
+func useClassicLine(line *themeLine, classic themeLine) {
+	if line.Style == "" {
+		line.Style = classic.Style
+	}
+	if line.Width == 0 {
+		line.Width = classic.Width
+	}
+	if line.Distance == 0 {
+		line.Distance = classic.Distance
+	}
+}
+
+// CheckThemeLine checks a court marking in a theme file makes sense, and works
+// out its colour.
+func checkThemeLine(what string, line *themeLine) error {
+	switch line.Style {
+	case NoLine, SolidLine, DashedLine:
+	default:
+		return errors.New("the " + what + " Style must be none, solid or dashed")
+	}
+	if line.Width < 1 || line.Width > FieldWidth/8 {
+		return fmt.Errorf("the %s Width must be between 1 and %d", what, FieldWidth/8)
+	}
+	var err error
+	line.r, line.g, line.b, err = parseColour(line.Colour)
+	if err != nil {
+		return fmt.Errorf("the %s Colour: %v", what, err)
+	}
+	return nil
+}
+
+// UseClassicPicture fills in a picture the theme file didn't mention. A
+// picture without a shape gets the classic shape, which is drawn with vector
+// graphics.
+func useClassicPicture(p *themePicture, classic themePicture) {
+	if p.Image == "" && p.Shape == "" {
+		p.Image = classic.Image
+	}
+	if p.Shape == "" {
+		p.Shape = classic.Shape
+	}
+}
+
+// CheckThemePicture checks a picture in a theme file makes sense, and works
+// out its colour. Shapes are the shapes the picture is allowed to be.
+func checkThemePicture(what string, p *themePicture, shapes []string) error {
+	if p.Image != "" {
+		// the digits are a picture for every score, and they must all be
+		// there, because loadGraphic stops the game if one is missing
+		var names []string
+		names = []string{p.Image}
+		if what == "Digits" {
+			if strings.Contains(p.Image, "%d") == false {
+				return errors.New("the Digits Image must have %d where the number goes, like graphics/%d.png")
+			}
+			names = nil
+			var score int
+			for score = 0; score <= HighestScore; score++ {
+				names = append(names, fmt.Sprintf(p.Image, score))
+			}
+		}
+		var name string
+		for _, name = range names {
+			var data []byte
+			var err error
+			data, err = readAsset(name)
+			if err == nil && len(data) == 0 {
+				err = errors.New(name + " is empty")
+			}
+			if err != nil {
+				return fmt.Errorf("the %s Image %s: %v", what, p.Image, err)
+			}
+		}
+	}
+	// the shape is drawn when there isn't a picture, or with vector graphics
+	var shape string
+	var found bool
+	for _, shape = range shapes {
+		if p.Shape == shape {
+			found = true
+		}
+	}
+	if found == false {
+		return errors.New("the " + what + " Shape must be " + strings.Join(shapes, " or "))
+	}
+	var err error
+	p.r, p.g, p.b, err = parseColour(p.Colour)
+	if err != nil {
+		return fmt.Errorf("the %s Colour: %v", what, err)
+	}
+	return nil
+}
+
+// ParseColour turns a colour like "#ff8000" into its red, green and blue.
+// No colour at all is white.
+func parseColour(colour string) (uint8, uint8, uint8, error) {
+	if colour == "" {
+		return 255, 255, 255, nil
+	}
+	if len(colour) != 7 || colour[0] != '#' {
+		return 0, 0, 0, errors.New(colour + " isn't a colour like #ff8000")
+	}
+	var n uint64
+	var err error
+	n, err = strconv.ParseUint(colour[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, errors.New(colour + " isn't a colour like #ff8000")
+	}
+	return uint8(n >> 16), uint8(n >> 8), uint8(n), nil
+}
+
+// ClassicTheme is the look the game has always had, with the built in
+// graphics on a black background.
+func classicTheme() *theme {
+	var t theme
+	t.Name = "CLASSIC"
+	t.Background = themePicture{Shape: RectangleShape, Colour: "#000000"}
+	t.CentreLine = themeLine{Style: NoLine, Width: 4}
+	t.Boundary = themeLine{Style: NoLine, Width: 4}
+	t.ServiceLines = themeLine{Style: NoLine, Width: 2, Distance: FieldWidth / 4}
+	t.LeftBat = themePicture{Image: "graphics/bat.png", Shape: RectangleShape}
+	t.RightBat = themePicture{Image: "graphics/bat.png", Shape: RectangleShape}
+	t.Ball = themePicture{Image: "graphics/ball.png", Shape: RectangleShape}
+	t.Bricks = themePicture{Shape: RectangleShape, Colour: "#ffffff"}
+	t.Digits = themePicture{Image: "graphics/%d.png", Shape: FontShape}
+	t.GameOver = themePicture{Image: "graphics/GameOver.png", Shape: FontShape}
+	return &t
+}
+
+// CurrentTheme is the theme being used.
+func currentTheme() *theme {
+	return themes[themeNumber]
+}
+
+// ---- Choosing a theme ----
+
+// UseTheme loads the pictures for the theme being used. The pictures for the
+// theme that was used before are thrown away.
+func useTheme() {
+	var i int
+	for i = 0; i < len(themes); i++ {
+		if i != themeNumber {
+			unloadThemePictures(themes[i])
+		}
+	}
+	var t *theme
+	t = currentTheme()
+	loadThemePicture(&t.Background, 1)
+	loadThemePicture(&t.LeftBat, 1)
+	loadThemePicture(&t.RightBat, 1)
+	loadThemePicture(&t.Ball, 1)
+	loadThemePicture(&t.Bricks, 1)
+	loadThemePicture(&t.Digits, HighestScore+1)
+	loadThemePicture(&t.GameOver, 1)
+}
+
+// NextTheme changes to the next theme, or the one before if step is -1.
+func nextTheme(step int) {
+	themeNumber = (themeNumber + step + len(themes)) % len(themes)
+	useTheme()
+}
+
+// LoadThemePicture loads the pictures for a themePicture, if it has any and
+// they haven't been loaded already. Vector graphics don't load any pictures.
+func loadThemePicture(p *themePicture, count int) {
+	if p.Image == "" || len(p.textures) > 0 || vectorGraphics == true {
+		return
+	}
+	if count == 1 {
+		p.textures = append(p.textures, loadGraphic(p.Image))
+		return
+	}
+	var i int
+	for i = 0; i < count; i++ {
+		p.textures = append(p.textures, loadGraphic(fmt.Sprintf(p.Image, i)))
+	}
+}
+
+// UnloadThemePictures throws away all the pictures a theme has loaded.
+func unloadThemePictures(t *theme) {
+	var pictures []*themePicture
+	pictures = []*themePicture{&t.Background, &t.LeftBat, &t.RightBat, &t.Ball, &t.Bricks, &t.Digits, &t.GameOver}
+	var p *themePicture
+	for _, p = range pictures {
+		var texture *sdl.Texture
+		for _, texture = range p.textures {
+			texture.Destroy()
+		}
+		p.textures = nil
+	}
+}
+
+// ---- Drawing with a theme ----
+
+// RenderBackground draws the court: the background, then the boundary
+// lines, the service areas and the net on top of it. Everything else is
+// drawn on top of the court.
+func renderBackground() {
+	var t *theme
+	t = currentTheme()
+	var field sdl.Rect
+	field = sdl.Rect{X: 0, Y: 0, W: int32(windowWidth), H: int32(windowHeight)}
+	renderThemePicture(&t.Background, field, untinted)
+
+	// the boundary lines are along the top and bottom edges, where the ball
+	// bounces. The ball is drawn over them.
+	var line themeLine
+	line = t.Boundary
+	renderCourtLine(line, 0, 0, windowWidth, line.Width)
+	renderCourtLine(line, 0, windowHeight-line.Width, windowWidth, line.Width)
+
+	// a line across the court near each end, and a line along the middle
+	// joining them. They are only there to look at.
+	line = t.ServiceLines
+	renderCourtLine(line, line.Distance-line.Width/2, 0, line.Width, windowHeight)
+	renderCourtLine(line, windowWidth-line.Distance-line.Width/2, 0, line.Width, windowHeight)
+	renderCourtLine(line, line.Distance, windowHeight/2-line.Width/2, windowWidth-line.Distance*2, line.Width)
+
+	line = t.CentreLine
+	// vector graphics always have a net, like the 1972 game
+	if line.Style == NoLine && vectorGraphics == true {
+		line.Style = DashedLine
+	}
+	renderCourtLine(line, windowWidth/2-line.Width/2, 0, line.Width, windowHeight)
+}
+
+// RenderCourtLine draws a court marking filling the rectangle at x and y that
+// is w wide and h high. A dashed line is dashed along its length.
+func renderCourtLine(line themeLine, x, y, w, h int) {
+	if line.Style == NoLine {
+		return
+	}
+	renderer.SetDrawColor(line.r, line.g, line.b, 255)
+	if line.Style == SolidLine {
+		renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(y), W: int32(w), H: int32(h)})
+	} else if h > w {
+		// a line up and down the court
+		var dashY, length int
+		for dashY = y; dashY < y+h; dashY = dashY + DashLength*2 {
+			// the last dash stops at the end of the line
+			length = DashLength
+			if dashY+length > y+h {
+				length = y + h - dashY
+			}
+			renderer.FillRect(&sdl.Rect{X: int32(x), Y: int32(dashY), W: int32(w), H: int32(length)})
+		}
+	} else {
+		// a line across the court
+		var dashX, length int
+		for dashX = x; dashX < x+w; dashX = dashX + DashLength*2 {
+			length = DashLength
+			if dashX+length > x+w {
+				length = x + w - dashX
+			}
+			renderer.FillRect(&sdl.Rect{X: int32(dashX), Y: int32(y), W: int32(length), H: int32(h)})
+		}
+	}
+	// put the draw colour back to black. renderer.Clear uses the draw colour.
+	renderer.SetDrawColor(0, 0, 0, 0)
+}
+
+// RenderThemePicture draws a themePicture filling dst. Tint is mixed with the
+// picture's own colour, so a player's bat colour shows up whatever the theme
+// is.
+func renderThemePicture(p *themePicture, dst sdl.Rect, tint batColour) {
+	renderFadedThemePicture(p, dst, tint, 255)
+}
+
+// RenderFadedThemePicture draws a themePicture that you can see through.
+// Alpha is how solid it is, from 0 for invisible to 255 for solid. Words
+// drawn with the font are always solid.
+func renderFadedThemePicture(p *themePicture, dst sdl.Rect, tint batColour, alpha uint8) {
+	var r, g, b uint8
+	r = uint8(int(p.r) * int(tint.r) / 255)
+	g = uint8(int(p.g) * int(tint.g) / 255)
+	b = uint8(int(p.b) * int(tint.b) / 255)
+	if len(p.textures) > 0 {
+		p.textures[0].SetColorMod(r, g, b)
+		// a picture without see through parts isn't blended unless we ask
+		if alpha < 255 {
+			p.textures[0].SetBlendMode(sdl.BLENDMODE_BLEND)
+		}
+		p.textures[0].SetAlphaMod(alpha)
+		renderer.Copy(p.textures[0], nil, &dst)
+		p.textures[0].SetAlphaMod(255)
+		return
+	}
+	// blending lets us draw colours that you can see through
+	if alpha < 255 {
+		renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	}
+	switch p.Shape {
+	case RectangleShape:
+		renderer.SetDrawColor(r, g, b, alpha)
+		renderer.FillRect(&dst)
+	case CircleShape:
+		renderer.SetDrawColor(r, g, b, alpha)
+		renderCircle(dst)
+	case FontShape:
+		renderFontText(p.text, dst, r, g, b)
+	}
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+	// put the draw colour back to black. renderer.Clear uses the draw colour.
+	renderer.SetDrawColor(0, 0, 0, 0)
+}
+
+// RenderThemeDigit draws a score filling dst.
+func renderThemeDigit(p *themePicture, dst sdl.Rect, score int) {
+	if score < len(p.textures) {
+		p.textures[score].SetColorMod(p.r, p.g, p.b)
+		renderer.Copy(p.textures[score], nil, &dst)
+		return
+	}
+	renderFontText(strconv.Itoa(score), dst, p.r, p.g, p.b)
+}
+
+// RenderCircle draws a filled circle that fits inside dst, one line across at
+// a time. The width of each line comes from Pythagoras' theorem.
+func renderCircle(dst sdl.Rect) {
+	var radius float64
+	radius = float64(dst.H) / 2
+	var centreX, centreY float64
+	centreX = float64(dst.X) + float64(dst.W)/2
+	centreY = float64(dst.Y) + radius
+	var row int32
+	for row = 0; row < dst.H; row++ {
+		var dy float64
+		dy = float64(row) + 0.5 - radius
+		var half float64
+		half = radius*radius - dy*dy
+		if half <= 0 {
+			continue
+		}
+		// the circle is stretched if dst isn't square
+		half = math.Sqrt(half) * float64(dst.W) / float64(dst.H)
+		renderer.DrawLine(int(centreX-half), int(centreY+dy), int(centreX+half), int(centreY+dy))
+	}
+}
+
+// RenderFontText draws some text with the squares from text.go, as big as
+// will fit in dst, in the middle of dst.
+func renderFontText(text string, dst sdl.Rect, r, g, b uint8) {
+	var scale int
+	scale = int(dst.H) / LetterHeight
+	if textWidth(text, 1) > 0 && int(dst.W)/textWidth(text, 1) < scale {
+		scale = int(dst.W) / textWidth(text, 1)
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	var y int
+	y = int(dst.Y) + (int(dst.H)-textHeight(scale))/2
+	renderTextCentred(text, int(dst.X)+int(dst.W)/2, y, scale, r, g, b)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLoadTheme(t *testing.T) {
+	var tests = []struct {
+		name  string
+		theme string
+		ok    bool
+	}{
+		{"the smallest theme", `{"Name": "test"}`, true},
+		{"shapes and colours", `{"Name": "test", "Ball": {"Shape": "circle", "Colour": "#ffff00"}, "Digits": {"Shape": "font"}}`, true},
+		{"digit pictures", `{"Name": "test", "Digits": {"Image": "graphics/digit%d.png"}}`, true},
+		{"not JSON", `{"Name": `, false},
+		{"no name", `{}`, false},
+		{"an unknown shape", `{"Name": "test", "Ball": {"Shape": "triangle"}}`, false},
+		{"round bricks", `{"Name": "test", "Bricks": {"Shape": "circle"}}`, false},
+		{"a bad colour", `{"Name": "test", "LeftBat": {"Colour": "#12"}}`, false},
+		{"a missing picture", `{"Name": "test", "RightBat": {"Image": "graphics/nothing.png"}}`, false},
+		{"an empty picture", `{"Name": "test", "Ball": {"Image": "graphics/empty.png"}}`, false},
+		{"digits without a number", `{"Name": "test", "Digits": {"Image": "graphics/digit.png"}}`, false},
+		{"some digits missing", `{"Name": "test", "Digits": {"Image": "graphics/half%d.png"}}`, false},
+		{"an unknown line style", `{"Name": "test", "Boundary": {"Style": "wiggly"}}`, false},
+		{"a line that is too wide", `{"Name": "test", "CentreLine": {"Width": 1000}}`, false},
+		{"service lines past the net", `{"Name": "test", "ServiceLines": {"Distance": 600}}`, false},
+	}
+	setUpTestMatch(t)
+	assetsDirectory = t.TempDir()
+	var png []byte
+	var err error
+	png, err = readAsset("graphics/ball.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a picture for every digit, and a set with only the low digits
+	var score int
+	for score = 0; score <= HighestScore; score++ {
+		writeTestFile(t, filepath.Join(assetsDirectory, "graphics", "digit"+strconv.Itoa(score)+".png"), png)
+		if score < HighestScore/2 {
+			writeTestFile(t, filepath.Join(assetsDirectory, "graphics", "half"+strconv.Itoa(score)+".png"), png)
+		}
+	}
+	writeTestFile(t, filepath.Join(assetsDirectory, "graphics", "empty.png"), nil)
+	var test struct {
+		name  string
+		theme string
+		ok    bool
+	}
+	for _, test = range tests {
+		writeTestFile(t, filepath.Join(assetsDirectory, "themes", "test.json"), []byte(test.theme))
+		_, err = loadTheme("test.json")
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: loaded a theme it should have turned away", test.name)
+		}
+	}
+}
@@ -0,0 +1,866 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/gophercoders/random"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- Tournaments ----
+//
+// A tournament is a set of matches between a group of players. The players
+// type their names in on the tournament screen, and choose whether each of
+// them is a person or the computer. Then the game works out who plays who.
+//
+// There are two kinds of tournament. In a knockout tournament the winner of
+// each match goes through to the next round, and the loser is out. In a
+// round robin tournament everybody plays everybody else, and the player who
+// wins the most matches is the champion.
+//
+// Between the matches the game shows the bracket, which is the list of
+// matches and their results. The tournament is saved after every match, so
+// it can be carried on another day.
+
+// The kinds of tournament
+const KnockoutFormat = 0
+const RoundRobinFormat = 1
+
+// The number of players in a tournament
+const MinimumTournamentPlayers = 2
+const MaximumTournamentPlayers = 16
+
+// The longest name a player can have, so the bracket fits on the screen.
+const MaximumNameLength = 12
+
+// A match's winner is NotPlayedYet until the match has been played. It must
+// not be the same as Bye, or a match won by a bye would look unplayed.
+const NotPlayedYet = -2
+
+// In a knockout tournament with an odd number of players, some players don't
+// have anyone to play in the first round. Their opponent is a Bye, and they
+// go straight through to the next round.
+const Bye = -1
+
+// The version of the tournament file. If the file changes, this must change
+// too, so an old file is not read wrongly.
+const TournamentFileVersion = 2
+
+// The name of the file the tournament is saved in.
+const TournamentFilename = "tournament.json"
+
+// The screens the tournament uses
+const TournamentSetupScreen = 0
+const TournamentBracketScreen = 1
+
+// A tournamentPlayer is one of the players in a tournament.
+type tournamentPlayer struct {
+	Name     string
+	Computer bool
+}
+
+// A tournamentMatch is one match in a tournament. Left and Right are the
+// numbers of the players in the tournament's list of players.
+type tournamentMatch struct {
+	Round      int
+	Left       int
+	Right      int
+	LeftScore  int
+	RightScore int
+	Winner     int
+	// two computers don't play each other, they toss a coin instead
+	CoinToss bool
+}
+
+// A tournament holds everything about a tournament. It is saved as JSON, so
+// the names start with capital letters.
+type tournament struct {
+	Version int
+	Format  int
+	Players []tournamentPlayer
+	Matches []tournamentMatch
+}
+
+// The tournament being set up or played.
+var theTournament tournament
+
+// A tournament saved by an earlier game that has not finished yet.
+var savedTournament tournament
+var haveSavedTournament bool
+
+// The inTournamentScreen flag is true while the tournament setup screen or
+// the bracket is on the screen.
+var inTournamentScreen bool
+
+// Which tournament screen is showing
+var tournamentScreen int
+
+// The line on the setup screen the player has selected. Line zero is the
+// format, then there is one line for each player, then "ADD PLAYER", "START"
+// and "RESUME SAVED TOURNAMENT".
+var tournamentSelection int
+
+// A message to show at the bottom of the tournament screens.
+var tournamentMessage string
+
+// The playingTournamentMatch flag is true while one of the tournament's
+// matches is being played, and tournamentMatchNumber is which one.
+var playingTournamentMatch bool
+var tournamentMatchNumber int
+
+// The twoPlayers flag is true when two people are playing on one computer.
+// Then the left player uses the W and S keys, the right player uses the up
+// and down cursor keys, and the computer doesn't move the right bat.
+var twoPlayers bool
+
+// ---- Making the matches ----
+
+// StartTournament works out the first matches and shows the bracket.
+func startTournament() {
+	var i, j int
+	for i = 0; i < len(theTournament.Players); i++ {
+		if theTournament.Players[i].Name == "" {
+			tournamentMessage = "EVERY PLAYER NEEDS A NAME"
+			return
+		}
+		for j = 0; j < i; j++ {
+			if theTournament.Players[i].Name == theTournament.Players[j].Name {
+				tournamentMessage = "TWO PLAYERS ARE CALLED " + theTournament.Players[i].Name
+				return
+			}
+		}
+	}
+	theTournament.Version = TournamentFileVersion
+	if theTournament.Format == KnockoutFormat {
+		theTournament.Matches = makeFirstKnockoutRound(len(theTournament.Players))
+	} else {
+		theTournament.Matches = makeRoundRobinMatches(len(theTournament.Players))
+	}
+	saveTournament()
+	showBracket()
+}
+
+// MakeFirstKnockoutRound makes the first round of a knockout tournament. The
+// number of places in the first round must be a power of two (2, 4, 8 or 16)
+// so that every round has half as many players as the one before. Any places
+// left over are byes. The players are seeded in the order they were typed
+// in, so the first two players can only meet in the final.
+func makeFirstKnockoutRound(numberOfPlayers int) []tournamentMatch {
+	var places []int
+	places = []int{0}
+	for len(places) < numberOfPlayers {
+		// each player is paired with the player the same distance from the
+		// other end of the list
+		var doubled []int
+		var place int
+		for _, place = range places {
+			doubled = append(doubled, place, 2*len(places)-1-place)
+		}
+		places = doubled
+	}
+	var matches []tournamentMatch
+	var k int
+	for k = 0; k+1 < len(places); k = k + 2 {
+		var m tournamentMatch
+		m.Round = 0
+		m.Left = places[k]
+		m.Right = places[k+1]
+		m.Winner = NotPlayedYet
+		if m.Right >= numberOfPlayers {
+			m.Right = Bye
+			m.Winner = m.Left
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// MakeRoundRobinMatches makes a match between every pair of players. It uses
+// the "circle method" to split the matches into rounds where everybody plays
+// once: the players sit in a circle, and after each round everybody except
+// the first player moves round one seat.
+func makeRoundRobinMatches(numberOfPlayers int) []tournamentMatch {
+	var seats []int
+	var i int
+	for i = 0; i < numberOfPlayers; i++ {
+		seats = append(seats, i)
+	}
+	// with an odd number of players somebody sits out each round
+	if numberOfPlayers%2 == 1 {
+		seats = append(seats, Bye)
+	}
+	var matches []tournamentMatch
+	var round int
+	for round = 0; round < len(seats)-1; round++ {
+		var k int
+		for k = 0; k < len(seats)/2; k++ {
+			var m tournamentMatch
+			m.Round = round
+			m.Left = seats[k]
+			m.Right = seats[len(seats)-1-k]
+			m.Winner = NotPlayedYet
+			if m.Left != Bye && m.Right != Bye {
+				matches = append(matches, m)
+			}
+		}
+		var last int
+		last = seats[len(seats)-1]
+		copy(seats[2:], seats[1:len(seats)-1])
+		seats[1] = last
+	}
+	return matches
+}
+
+// AdvanceKnockout makes the next round of a knockout tournament, once every
+// match in the last round has been played.
+func advanceKnockout() {
+	var last int
+	last = lastTournamentRound()
+	var winners []int
+	var m tournamentMatch
+	for _, m = range theTournament.Matches {
+		if m.Round != last {
+			continue
+		}
+		if m.Winner == NotPlayedYet {
+			return
+		}
+		winners = append(winners, m.Winner)
+	}
+	// the final has been played
+	if len(winners) < 2 {
+		return
+	}
+	var k int
+	for k = 0; k+1 < len(winners); k = k + 2 {
+		var next tournamentMatch
+		next.Round = last + 1
+		next.Left = winners[k]
+		next.Right = winners[k+1]
+		next.Winner = NotPlayedYet
+		theTournament.Matches = append(theTournament.Matches, next)
+	}
+}
+
+// LastTournamentRound finds the number of the latest round.
+func lastTournamentRound() int {
+	var last int
+	var m tournamentMatch
+	for _, m = range theTournament.Matches {
+		if m.Round > last {
+			last = m.Round
+		}
+	}
+	return last
+}
+
+// NextTournamentMatch finds the next match to play, or returns -1 if there
+// isn't one.
+func nextTournamentMatch(t tournament) int {
+	var i int
+	for i = 0; i < len(t.Matches); i++ {
+		if t.Matches[i].Winner == NotPlayedYet {
+			return i
+		}
+	}
+	return -1
+}
+
+// TournamentFinished is true when every match has been played. A knockout
+// tournament might still need another round, but advanceKnockout always
+// makes the next round as soon as the last one is finished.
+func tournamentFinished(t tournament) bool {
+	return len(t.Matches) > 0 && nextTournamentMatch(t) == -1
+}
+
+// ---- Playing the matches ----
+
+// PlayNextTournamentMatch starts the next match.
+func playNextTournamentMatch() {
+	var i int
+	i = nextTournamentMatch(theTournament)
+	if i == -1 {
+		return
+	}
+	var m *tournamentMatch
+	m = &theTournament.Matches[i]
+	if theTournament.Players[m.Left].Computer == true && theTournament.Players[m.Right].Computer == true {
+		// two computers toss a coin rather than make everybody watch them
+		m.CoinToss = true
+		if random.GetRandomNumberInRange(1, 2) == 1 {
+			m.Winner = m.Left
+		} else {
+			m.Winner = m.Right
+		}
+		tournamentMessage = theTournament.Players[m.Winner].Name + " WON THE COIN TOSS"
+		finishTournamentMatch()
+		return
+	}
+	// a person always plays on the left, so the computer can play on the right
+	if theTournament.Players[m.Left].Computer == true {
+		m.Left, m.Right = m.Right, m.Left
+	}
+	twoPlayers = theTournament.Players[m.Right].Computer == false
+	tournamentMatchNumber = i
+	playingTournamentMatch = true
+	inTournamentScreen = false
+	tournamentMessage = ""
+	startNewGame()
+}
+
+// RecordTournamentResult records the result of the match that has just
+// finished. A match that ends in a draw, which can happen in breakout, is
+// played again.
+func recordTournamentResult() {
+	var m *tournamentMatch
+	m = &theTournament.Matches[tournamentMatchNumber]
+	if myScore == computersScore {
+		startNewGame()
+		return
+	}
+	m.LeftScore = myScore
+	m.RightScore = computersScore
+	if myScore > computersScore {
+		m.Winner = m.Left
+	} else {
+		m.Winner = m.Right
+	}
+	tournamentMessage = ""
+	finishTournamentMatch()
+}
+
+// FinishTournamentMatch saves the tournament after a match and goes back to
+// the bracket.
+func finishTournamentMatch() {
+	if theTournament.Format == KnockoutFormat {
+		advanceKnockout()
+	}
+	saveTournament()
+	showBracket()
+}
+
+// LeaveTournamentMatch stops the match without recording a result.
+func leaveTournamentMatch() {
+	showBracket()
+	startNewGame()
+}
+
+// MoveBatsForTwoPlayers moves both bats when two people are playing on one
+// computer. The computer only repeats the last key that was pressed, so if
+// we waited for key presses only one player could move at a time. Instead we
+// look at which keys are held down, every frame.
+func moveBatsForTwoPlayers() {
+	var left, right int
+	// a replay remembers which keys were held down
+	if playingBack == true {
+		left = playbackLeftHeld
+		right = playbackRightHeld
+	} else {
+		left, right = readTwoPlayerKeys()
+		recordHeldKeys(left, right)
+	}
+	myBatY = moveBatForInput(myBatY, myBatH, myBatStep(), left)
+	computersBatY = moveBatForInput(computersBatY, computersBatH, computersBatStep(), right)
+}
+
+// ReadTwoPlayerKeys finds out which keys the two players are holding down.
+func readTwoPlayerKeys() (int, int) {
+	var keys []uint8
+	keys = sdl.GetKeyboardState()
+	var left, right int
+	if len(keys) > int(sdl.SCANCODE_W) && len(keys) > int(sdl.SCANCODE_S) && len(keys) > int(sdl.SCANCODE_DOWN) {
+		if keys[sdl.SCANCODE_W] != 0 {
+			left = left | TickInputUp
+		}
+		if keys[sdl.SCANCODE_S] != 0 {
+			left = left | TickInputDown
+		}
+		if keys[sdl.SCANCODE_UP] != 0 {
+			right = right | TickInputUp
+		}
+		if keys[sdl.SCANCODE_DOWN] != 0 {
+			right = right | TickInputDown
+		}
+	}
+	return left, right
+}
+
+// ---- Saving ----
+
+// SaveTournament saves the tournament to a file.
+func saveTournament() {
+	var data []byte
+	var err error
+	data, err = json.MarshalIndent(theTournament, "", "  ")
+	if err == nil {
+		err = os.WriteFile(userFilePath(TournamentFilename), data, 0644)
+	}
+	if err != nil {
+		tournamentMessage = "COULD NOT SAVE THE TOURNAMENT"
+	}
+}
+
+// LoadTournament loads the saved tournament, and checks it makes sense.
+func loadTournament() (tournament, error) {
+	var t tournament
+	var data []byte
+	var err error
+	data, err = os.ReadFile(userFilePath(TournamentFilename))
+	if err != nil {
+		return t, err
+	}
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		return t, err
+	}
+	if t.Version != TournamentFileVersion {
+		return t, errors.New("the tournament was saved by a different version of pong")
+	}
+	if t.Format != KnockoutFormat && t.Format != RoundRobinFormat {
+		return t, errors.New("the tournament has an unknown format")
+	}
+	if len(t.Players) < MinimumTournamentPlayers || len(t.Players) > MaximumTournamentPlayers {
+		return t, errors.New("the tournament has the wrong number of players")
+	}
+	var m tournamentMatch
+	for _, m = range t.Matches {
+		if m.Left < 0 || m.Left >= len(t.Players) || m.Right < Bye || m.Right >= len(t.Players) {
+			return t, errors.New("a tournament match has a player who isn't in the tournament")
+		}
+		// byes are only in the first round of a knockout, and the player
+		// who has the bye has already won
+		if m.Right == Bye && (t.Format != KnockoutFormat || m.Round != 0 || m.Winner != m.Left) {
+			return t, errors.New("a tournament match has a bye where there can't be one")
+		}
+		if m.Winner != NotPlayedYet && m.Winner != m.Left && m.Winner != m.Right {
+			return t, errors.New("a tournament match was won by a player who wasn't playing")
+		}
+	}
+	return t, nil
+}
+
+// ---- The tournament screens ----
+
+// OpenTournamentScreen shows the tournament setup screen.
+func openTournamentScreen() {
+	inMenu = false
+	inTournamentScreen = true
+	tournamentScreen = TournamentSetupScreen
+	tournamentSelection = 0
+	tournamentMessage = ""
+	theTournament = tournament{}
+	theTournament.Format = KnockoutFormat
+	theTournament.Players = []tournamentPlayer{
+		{Name: "PLAYER 1", Computer: false},
+		{Name: "COMPUTER", Computer: true},
+	}
+	var err error
+	savedTournament, err = loadTournament()
+	haveSavedTournament = err == nil && tournamentFinished(savedTournament) == false
+}
+
+// ShowBracket shows the bracket between matches.
+func showBracket() {
+	inTournamentScreen = true
+	tournamentScreen = TournamentBracketScreen
+	playingTournamentMatch = false
+	twoPlayers = false
+}
+
+// CloseTournamentScreen goes back to the menu. The tournament has already
+// been saved, so it can be resumed later.
+func closeTournamentScreen() {
+	inTournamentScreen = false
+	playingTournamentMatch = false
+	twoPlayers = false
+	inMenu = true
+}
+
+// HandleTournamentEvent responds to the keys pressed on the tournament
+// screens and during a tournament match, for the keys that do something
+// different from a normal game. It returns true if it dealt with the key.
+func handleTournamentEvent(event sdl.Event) bool {
+	if inTournamentScreen == false && playingTournamentMatch == false {
+		return false
+	}
+	if isKeyDownEvent(event) == false {
+		return inTournamentScreen
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	if inTournamentScreen == true {
+		if tournamentScreen == TournamentSetupScreen {
+			handleTournamentSetupKey(keyDownEvt.Keysym.Sym)
+		} else {
+			handleBracketKey(keyDownEvt.Keysym.Sym)
+		}
+		return true
+	}
+	switch keyDownEvt.Keysym.Sym {
+	case sdl.K_ESCAPE:
+		leaveTournamentMatch()
+		return true
+	case sdl.K_RETURN:
+		if gameOver == true {
+			recordTournamentResult()
+		}
+		return true
+	case sdl.K_UP, sdl.K_DOWN, sdl.K_w, sdl.K_s:
+		// with two players the bats are moved by moveBatsForTwoPlayers
+		return twoPlayers
+	}
+	return false
+}
+
+// NumberOfTournamentSetupLines counts the lines on the setup screen.
+func numberOfTournamentSetupLines() int {
+	var lines int
+	lines = 1 + len(theTournament.Players) + 2
+	if haveSavedTournament == true {
+		lines = lines + 1
+	}
+	return lines
+}
+
+// HandleTournamentSetupKey responds to a key pressed on the setup screen.
+func handleTournamentSetupKey(key sdl.Keycode) {
+	var addLine, startLine, resumeLine int
+	addLine = 1 + len(theTournament.Players)
+	startLine = addLine + 1
+	resumeLine = startLine + 1
+	// the number of the selected player, if a player is selected
+	var player int
+	player = tournamentSelection - 1
+	var playerSelected bool
+	playerSelected = player >= 0 && player < len(theTournament.Players)
+
+	switch key {
+	case sdl.K_UP:
+		if tournamentSelection > 0 {
+			tournamentSelection = tournamentSelection - 1
+		}
+	case sdl.K_DOWN:
+		if tournamentSelection < numberOfTournamentSetupLines()-1 {
+			tournamentSelection = tournamentSelection + 1
+		}
+	case sdl.K_LEFT, sdl.K_RIGHT:
+		if tournamentSelection == 0 {
+			if theTournament.Format == KnockoutFormat {
+				theTournament.Format = RoundRobinFormat
+			} else {
+				theTournament.Format = KnockoutFormat
+			}
+		} else if playerSelected == true {
+			theTournament.Players[player].Computer = !theTournament.Players[player].Computer
+		}
+	case sdl.K_RETURN:
+		if tournamentSelection == addLine && len(theTournament.Players) < MaximumTournamentPlayers {
+			var p tournamentPlayer
+			p.Name = "PLAYER " + strconv.Itoa(len(theTournament.Players)+1)
+			theTournament.Players = append(theTournament.Players, p)
+			tournamentSelection = tournamentSelection + 1
+		} else if tournamentSelection == startLine {
+			startTournament()
+		} else if tournamentSelection == resumeLine && haveSavedTournament == true {
+			theTournament = savedTournament
+			tournamentMessage = ""
+			showBracket()
+		}
+	case sdl.K_BACKSPACE:
+		if playerSelected == false {
+			return
+		}
+		var name string
+		name = theTournament.Players[player].Name
+		if len(name) > 0 {
+			theTournament.Players[player].Name = name[:len(name)-1]
+		} else if len(theTournament.Players) > MinimumTournamentPlayers {
+			// backspace on an empty name removes the player
+			theTournament.Players = append(theTournament.Players[:player], theTournament.Players[player+1:]...)
+		}
+	case sdl.K_ESCAPE:
+		closeTournamentScreen()
+	default:
+		var letter rune
+		var ok bool
+		letter, ok = keyToLetter(key)
+		if ok == true && playerSelected == true && len(theTournament.Players[player].Name) < MaximumNameLength {
+			theTournament.Players[player].Name = theTournament.Players[player].Name + string(letter)
+		}
+	}
+}
+
+// KeyToLetter works out which letter or number a key types. The font only
+// has capital letters, so the names are always in capitals.
+func keyToLetter(key sdl.Keycode) (rune, bool) {
+	if key >= sdl.K_a && key <= sdl.K_z {
+		return 'A' + rune(key-sdl.K_a), true
+	}
+	if key >= sdl.K_0 && key <= sdl.K_9 {
+		return '0' + rune(key-sdl.K_0), true
+	}
+	if key == sdl.K_SPACE {
+		return ' ', true
+	}
+	return 0, false
+}
+
+// HandleBracketKey responds to a key pressed while the bracket is showing.
+func handleBracketKey(key sdl.Keycode) {
+	switch key {
+	case sdl.K_RETURN:
+		playNextTournamentMatch()
+	case sdl.K_ESCAPE:
+		closeTournamentScreen()
+	}
+}
+
+// RenderTournamentScreen draws whichever tournament screen is showing.
+func renderTournamentScreen() {
+	if tournamentScreen == TournamentSetupScreen {
+		renderTournamentSetup()
+	} else {
+		renderBracket()
+	}
+	if tournamentMessage != "" {
+		renderTextCentred(tournamentMessage, windowWidth/2, windowHeight-80, 2, 255, 64, 64)
+	}
+}
+
+// RenderTournamentSetup draws the setup screen.
+func renderTournamentSetup() {
+	renderTextCentred("TOURNAMENT", windowWidth/2, 48, 8, 255, 255, 255)
+	var format string
+	if theTournament.Format == KnockoutFormat {
+		format = "KNOCKOUT"
+	} else {
+		format = "ROUND ROBIN"
+	}
+	var y int
+	y = 140
+	renderTournamentLine(0, "FORMAT: "+format, y)
+	var i int
+	for i = 0; i < len(theTournament.Players); i++ {
+		var who string
+		if theTournament.Players[i].Computer == true {
+			who = "COMPUTER"
+		} else {
+			who = "PERSON"
+		}
+		renderTournamentLine(i+1, theTournament.Players[i].Name+" - "+who, y+(i+1)*28)
+	}
+	var line int
+	line = len(theTournament.Players) + 1
+	renderTournamentLine(line, "ADD PLAYER", y+line*28+8)
+	renderTournamentLine(line+1, "START", y+(line+1)*28+8)
+	if haveSavedTournament == true {
+		renderTournamentLine(line+2, "RESUME SAVED TOURNAMENT", y+(line+2)*28+8)
+	}
+	renderTextCentred("TYPE TO CHANGE A NAME, LEFT/RIGHT FOR PERSON OR COMPUTER, ESCAPE FOR THE MENU",
+		windowWidth/2, windowHeight-40, 2, 128, 128, 128)
+}
+
+// RenderTournamentLine draws one line of the setup screen. The selected line
+// is drawn in yellow with arrows next to it.
+func renderTournamentLine(line int, text string, y int) {
+	if line == tournamentSelection {
+		renderTextCentred("> "+text+" <", windowWidth/2, y, 3, 255, 255, 0)
+	} else {
+		renderTextCentred(text, windowWidth/2, y, 3, 255, 255, 255)
+	}
+}
+
+// RenderBracket draws the matches and results, and who plays next.
+func renderBracket() {
+	renderTextCentred("TOURNAMENT", windowWidth/2, 24, 6, 255, 255, 255)
+	if theTournament.Format == KnockoutFormat {
+		renderKnockoutBracket()
+	} else {
+		renderRoundRobinTable()
+	}
+	var next int
+	next = nextTournamentMatch(theTournament)
+	if next == -1 {
+		renderTextCentred("CHAMPION: "+tournamentChampion(), windowWidth/2, windowHeight-120, 4, 255, 255, 0)
+		renderTextCentred("ESCAPE FOR THE MENU", windowWidth/2, windowHeight-40, 2, 128, 128, 128)
+		return
+	}
+	var m tournamentMatch
+	m = theTournament.Matches[next]
+	renderTextCentred("NEXT: "+theTournament.Players[m.Left].Name+" V "+theTournament.Players[m.Right].Name,
+		windowWidth/2, windowHeight-120, 3, 255, 255, 0)
+	renderTextCentred("RETURN TO PLAY THE NEXT MATCH, ESCAPE FOR THE MENU",
+		windowWidth/2, windowHeight-40, 2, 128, 128, 128)
+}
+
+// RenderKnockoutBracket draws each round of a knockout tournament in a
+// column, with the first round on the left.
+func renderKnockoutBracket() {
+	var rounds int
+	rounds = 1
+	var places int
+	places = 2
+	for places < len(theTournament.Players) {
+		places = places * 2
+		rounds = rounds + 1
+	}
+	var columnW int
+	columnW = windowWidth / rounds
+	var top, height int
+	top = 100
+	height = windowHeight - 260
+	var next int
+	next = nextTournamentMatch(theTournament)
+	var round int
+	for round = 0; round < rounds; round++ {
+		// the matches in this round
+		var numbers []int
+		var i int
+		for i = 0; i < len(theTournament.Matches); i++ {
+			if theTournament.Matches[i].Round == round {
+				numbers = append(numbers, i)
+			}
+		}
+		var k int
+		for k = 0; k < len(numbers); k++ {
+			var x, y int
+			x = round*columnW + 16
+			y = top + (2*k+1)*height/(2*len(numbers)) - 10
+			renderBracketMatch(theTournament.Matches[numbers[k]], numbers[k] == next, x, y)
+		}
+	}
+}
+
+// RenderBracketMatch draws one match as two lines, one for each player. The
+// winner is drawn in white and the loser in grey. The next match is yellow.
+func renderBracketMatch(m tournamentMatch, isNext bool, x, y int) {
+	renderBracketPlayer(m, m.Left, m.LeftScore, isNext, x, y)
+	renderBracketPlayer(m, m.Right, m.RightScore, isNext, x, y+18)
+}
+
+// RenderBracketPlayer draws one player's line of a match.
+func renderBracketPlayer(m tournamentMatch, player int, score int, isNext bool, x, y int) {
+	var name string
+	if player == Bye {
+		name = "BYE"
+	} else {
+		name = theTournament.Players[player].Name
+	}
+	var result string
+	if m.Winner == NotPlayedYet || m.Right == Bye {
+		result = ""
+	} else if m.CoinToss == true {
+		result = "-"
+	} else {
+		result = strconv.Itoa(score)
+	}
+	var r, g, b uint8
+	r, g, b = 255, 255, 255
+	if isNext == true {
+		b = 0
+	} else if m.Winner != NotPlayedYet && m.Winner != player {
+		r, g, b = 128, 128, 128
+	}
+	renderText(fmt.Sprintf("%-12s %2s", name, result), x, y, 2, r, g, b)
+}
+
+// A standing is how well one player is doing in a round robin tournament.
+type standing struct {
+	player        int
+	played        int
+	won           int
+	lost          int
+	pointsFor     int
+	pointsAgainst int
+}
+
+// RoundRobinStandings works out the table for a round robin tournament. The
+// player who has won the most matches is at the top. If two players have won
+// the same number, the one who has scored the most more than their
+// opponents goes first.
+func roundRobinStandings() []standing {
+	var table []standing
+	table = make([]standing, len(theTournament.Players))
+	var i int
+	for i = 0; i < len(table); i++ {
+		table[i].player = i
+	}
+	var m tournamentMatch
+	for _, m = range theTournament.Matches {
+		if m.Winner == NotPlayedYet {
+			continue
+		}
+		table[m.Left].played = table[m.Left].played + 1
+		table[m.Right].played = table[m.Right].played + 1
+		table[m.Left].pointsFor = table[m.Left].pointsFor + m.LeftScore
+		table[m.Left].pointsAgainst = table[m.Left].pointsAgainst + m.RightScore
+		table[m.Right].pointsFor = table[m.Right].pointsFor + m.RightScore
+		table[m.Right].pointsAgainst = table[m.Right].pointsAgainst + m.LeftScore
+		if m.Winner == m.Left {
+			table[m.Left].won = table[m.Left].won + 1
+			table[m.Right].lost = table[m.Right].lost + 1
+		} else {
+			table[m.Right].won = table[m.Right].won + 1
+			table[m.Left].lost = table[m.Left].lost + 1
+		}
+	}
+	sort.SliceStable(table, func(a, b int) bool {
+		if table[a].won != table[b].won {
+			return table[a].won > table[b].won
+		}
+		return table[a].pointsFor-table[a].pointsAgainst > table[b].pointsFor-table[b].pointsAgainst
+	})
+	return table
+}
+
+// RenderRoundRobinTable draws the table for a round robin tournament.
+func renderRoundRobinTable() {
+	var x, y int
+	x = windowWidth/2 - 240
+	y = 100
+	renderText(fmt.Sprintf("%-12s %3s %3s %3s %4s %4s", "PLAYER", "P", "W", "L", "FOR", "AGST"), x, y, 2, 128, 128, 128)
+	var table []standing
+	table = roundRobinStandings()
+	var i int
+	for i = 0; i < len(table); i++ {
+		var s standing
+		s = table[i]
+		renderText(fmt.Sprintf("%-12s %3d %3d %3d %4d %4d", theTournament.Players[s.player].Name,
+			s.played, s.won, s.lost, s.pointsFor, s.pointsAgainst), x, y+(i+1)*24, 2, 255, 255, 255)
+	}
+	var played int
+	var m tournamentMatch
+	for _, m = range theTournament.Matches {
+		if m.Winner != NotPlayedYet {
+			played = played + 1
+		}
+	}
+	renderTextCentred(fmt.Sprintf("%d OF %d MATCHES PLAYED", played, len(theTournament.Matches)),
+		windowWidth/2, windowHeight-160, 2, 128, 128, 128)
+}
+
+// TournamentChampion finds the name of the winner of the tournament.
+func tournamentChampion() string {
+	if theTournament.Format == RoundRobinFormat {
+		return theTournament.Players[roundRobinStandings()[0].player].Name
+	}
+	var final tournamentMatch
+	final = theTournament.Matches[len(theTournament.Matches)-1]
+	return theTournament.Players[final.Winner].Name
+}
+
+// RenderTournamentNames shows who is playing during a tournament match.
+func renderTournamentNames() {
+	var m tournamentMatch
+	m = theTournament.Matches[tournamentMatchNumber]
+	renderTextCentred(theTournament.Players[m.Left].Name, windowWidth/4, windowHeight-40, 3, 128, 128, 128)
+	renderTextCentred(theTournament.Players[m.Right].Name, windowWidth*3/4, windowHeight-40, 3, 128, 128, 128)
+	if gameOver == true {
+		renderTextCentred("RETURN FOR THE TOURNAMENT", windowWidth/2, windowHeight/2+120, 3, 255, 255, 0)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestKnockoutBracket(t *testing.T) {
+	var matches []tournamentMatch
+	matches = makeFirstKnockoutRound(5)
+	// 5 players need 8 places, so there are 3 byes
+	if len(matches) != 4 {
+		t.Fatalf("%d matches in the first round", len(matches))
+	}
+	var byes int
+	var m tournamentMatch
+	for _, m = range matches {
+		if m.Right == Bye {
+			byes = byes + 1
+			if m.Winner != m.Left {
+				t.Errorf("player %d hasn't won their bye", m.Left)
+			}
+		}
+	}
+	if byes != 3 {
+		t.Errorf("%d byes", byes)
+	}
+	// the first two seeds are in different halves, so they can only meet in
+	// the final
+	if matches[0].Left != 0 || matches[len(matches)/2].Left != 1 {
+		t.Errorf("the seeds are in the wrong places: %+v", matches)
+	}
+}
+
+func TestRoundRobinMatches(t *testing.T) {
+	var players int
+	for players = MinimumTournamentPlayers; players <= MaximumTournamentPlayers; players++ {
+		var matches []tournamentMatch
+		matches = makeRoundRobinMatches(players)
+		if len(matches) != players*(players-1)/2 {
+			t.Errorf("%d players have %d matches", players, len(matches))
+		}
+		// everybody plays everybody else once, and nobody plays twice in a
+		// round
+		var met map[[2]int]bool
+		met = make(map[[2]int]bool)
+		var playing map[[2]int]bool
+		playing = make(map[[2]int]bool)
+		var m tournamentMatch
+		for _, m = range matches {
+			if m.Left == Bye || m.Right == Bye {
+				t.Fatalf("%d players have a bye", players)
+			}
+			var pair [2]int
+			pair = [2]int{m.Left, m.Right}
+			if m.Left > m.Right {
+				pair = [2]int{m.Right, m.Left}
+			}
+			if met[pair] == true {
+				t.Errorf("%d players: %d and %d meet twice", players, m.Left, m.Right)
+			}
+			met[pair] = true
+			if playing[[2]int{m.Round, m.Left}] == true || playing[[2]int{m.Round, m.Right}] == true {
+				t.Errorf("%d players: somebody plays twice in round %d", players, m.Round)
+			}
+			playing[[2]int{m.Round, m.Left}] = true
+			playing[[2]int{m.Round, m.Right}] = true
+		}
+	}
+}
+
+func TestRoundRobinStandings(t *testing.T) {
+	theTournament = tournament{Format: RoundRobinFormat}
+	theTournament.Players = []tournamentPlayer{{Name: "ANN"}, {Name: "BOB"}, {Name: "CAT"}}
+	theTournament.Matches = []tournamentMatch{
+		{Left: 0, Right: 1, LeftScore: 11, RightScore: 9, Winner: 0},
+		{Left: 1, Right: 2, LeftScore: 11, RightScore: 2, Winner: 1},
+		{Left: 2, Right: 0, LeftScore: 11, RightScore: 3, Winner: 2},
+	}
+	// everybody has won once, so the points decide: BOB +7, ANN -6, CAT -1
+	var table []standing
+	table = roundRobinStandings()
+	var want = []int{1, 2, 0}
+	var i int
+	for i = 0; i < len(want); i++ {
+		if table[i].player != want[i] || table[i].played != 2 || table[i].won != 1 {
+			t.Errorf("place %d is %+v", i+1, table[i])
+		}
+	}
+}
+
+func TestLoadTournament(t *testing.T) {
+	var tests = []struct {
+		name   string
+		format int
+		change func(tt *tournament)
+		ok     bool
+	}{
+		{"a good knockout", KnockoutFormat, func(tt *tournament) {}, true},
+		{"a good round robin", RoundRobinFormat, func(tt *tournament) {}, true},
+		{"a different version", KnockoutFormat, func(tt *tournament) { tt.Version = TournamentFileVersion + 1 }, false},
+		{"an unknown format", KnockoutFormat, func(tt *tournament) { tt.Format = 2 }, false},
+		{"too few players", KnockoutFormat, func(tt *tournament) { tt.Players = tt.Players[:1] }, false},
+		{"a player who isn't there", KnockoutFormat, func(tt *tournament) { tt.Matches[1].Left = 3 }, false},
+		{"a winner who wasn't playing", RoundRobinFormat, func(tt *tournament) {
+			// the players are 0, 1 and 2, so this is the one who isn't playing
+			tt.Matches[0].Winner = 3 - tt.Matches[0].Left - tt.Matches[0].Right
+		}, false},
+		{"a bye in a round robin", RoundRobinFormat, func(tt *tournament) {
+			tt.Matches[0].Right = Bye
+			tt.Matches[0].Winner = tt.Matches[0].Left
+		}, false},
+		{"a bye after the first round", KnockoutFormat, func(tt *tournament) { tt.Matches[0].Round = 1 }, false},
+		{"a bye that hasn't been won", KnockoutFormat, func(tt *tournament) { tt.Matches[0].Winner = NotPlayedYet }, false},
+		{"a match won by a bye", KnockoutFormat, func(tt *tournament) { tt.Matches[0].Winner = Bye }, false},
+		{"a winner that isn't a player", RoundRobinFormat, func(tt *tournament) { tt.Matches[0].Winner = Bye }, false},
+	}
+	var test struct {
+		name   string
+		format int
+		change func(tt *tournament)
+		ok     bool
+	}
+	for _, test = range tests {
+		setUpTestMatch(t)
+		// three players, so the knockout starts with a bye for the first
+		// player
+		var tt tournament
+		tt.Version = TournamentFileVersion
+		tt.Format = test.format
+		tt.Players = []tournamentPlayer{{Name: "ANN"}, {Name: "BOB"}, {Name: "COMPUTER", Computer: true}}
+		if test.format == KnockoutFormat {
+			tt.Matches = makeFirstKnockoutRound(len(tt.Players))
+		} else {
+			tt.Matches = makeRoundRobinMatches(len(tt.Players))
+		}
+		test.change(&tt)
+		writeTestJSON(t, userFilePath(TournamentFilename), tt)
+		var err error
+		_, err = loadTournament()
+		if test.ok == true && err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+		if test.ok == false && err == nil {
+			t.Errorf("%s: loaded a tournament it should have turned away", test.name)
+		}
+	}
+}
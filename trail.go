@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- The ball's trail ----
+//
+// A fast ball can be hard to follow, especially on a projector. The trail
+// draws faded copies of the ball where it was on the last few updates, so you
+// can see which way it is going. The oldest copies are the most see through.
+//
+// The faster the ball is going the longer the trail is. At the normal ball
+// speed half of the trail is drawn, and at twice the normal speed all of it
+// is. A ball that has stopped has no trail.
+//
+// The trail only changes how the game looks, so it is worked out from where
+// the ball is drawn. That means it works in network games and replays too.
+
+// The longest trail we allow, in updates.
+const MaxTrailLength = 30
+
+// How solid the newest copy of the ball in the trail is, from 0 for
+// invisible to 255 for solid.
+const TrailAlpha = 160
+
+// How many of the ball's last positions the trail can use. 0 means there is
+// no trail.
+var trailLength int
+
+// Where the ball was on the last few updates. The positions are kept in a
+// ring buffer, like the instant replay's (see instantreplay.go).
+var trailX [MaxTrailLength + 1]float64
+var trailY [MaxTrailLength + 1]float64
+var nextTrailPosition int
+var trailPositionCount int
+
+// AddTrailFlags adds the command line flag for the ball's trail.
+func addTrailFlags() {
+	flag.IntVar(&trailLength, "trail", 0, "how many of the ball's last positions are drawn behind it. 0 turns the trail off")
+}
+
+// CheckTrailFlags makes sure the trail isn't too long.
+func checkTrailFlags() error {
+	if trailLength < 0 || trailLength > MaxTrailLength {
+		return badSetting("trail", fmt.Sprintf("the trail must be between 0 and %d positions long", MaxTrailLength))
+	}
+	return nil
+}
+
+// ClearBallTrail forgets where the ball has been.
+func clearBallTrail() {
+	nextTrailPosition = 0
+	trailPositionCount = 0
+}
+
+// UpdateBallTrail remembers where the ball is after this update. It is called
+// once every update (see updateGame in pong.go). We keep one more position than the trail is long, so the
+// newest copy in the trail isn't drawn under the ball.
+func updateBallTrail() {
+	if trailLength == 0 {
+		return
+	}
+	if trailPositionCount > 0 {
+		var last int
+		last = (nextTrailPosition + MaxTrailLength) % (MaxTrailLength + 1)
+		// a ball that hasn't moved, because the game is paused or the
+		// menu is on the screen, leaves the trail as it was
+		if trailX[last] == ballX && trailY[last] == ballY {
+			return
+		}
+		// a ball that has jumped further than it could go in one update
+		// has been put back in the middle after a point
+		if math.Abs(trailX[last]-ballX) > float64(windowWidth)/4 {
+			clearBallTrail()
+		}
+	}
+	trailX[nextTrailPosition] = ballX
+	trailY[nextTrailPosition] = ballY
+	nextTrailPosition = (nextTrailPosition + 1) % (MaxTrailLength + 1)
+	if trailPositionCount < trailLength+1 {
+		trailPositionCount = trailPositionCount + 1
+	}
+}
+
+// BallTrailLength works out how many copies of the ball to draw, from how far
+// the ball moved on the last update.
+func ballTrailLength() int {
+	if trailPositionCount < 2 {
+		return 0
+	}
+	var newest, before int
+	newest = (nextTrailPosition + MaxTrailLength) % (MaxTrailLength + 1)
+	before = (nextTrailPosition + MaxTrailLength - 1) % (MaxTrailLength + 1)
+	var speed float64
+	speed = math.Hypot(trailX[newest]-trailX[before], trailY[newest]-trailY[before]) * UpdatesPerSecond
+	var length int
+	length = int(math.Round(float64(trailLength) * speed / float64(2*ballSpeed)))
+	if length > trailLength {
+		length = trailLength
+	}
+	if length > trailPositionCount-1 {
+		length = trailPositionCount - 1
+	}
+	return length
+}
+
+// RenderBallTrail draws the faded copies of the ball, the oldest first so the
+// newer ones are on top.
+func renderBallTrail() {
+	var length int
+	length = ballTrailLength()
+	var i int
+	for i = length; i >= 1; i-- {
+		// i is how many updates ago the ball was there
+		var position int
+		position = (nextTrailPosition + MaxTrailLength - i) % (MaxTrailLength + 1)
+		var dst sdl.Rect
+		dst.X = int32(trailX[position])
+		dst.Y = int32(trailY[position])
+		dst.W = int32(ballW)
+		dst.H = int32(ballH)
+		var alpha int
+		alpha = TrailAlpha * (length + 1 - i) / (length + 1)
+		renderFadedThemePicture(&currentTheme().Ball, dst, untinted, uint8(alpha))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ---- The player's files ----
+//
+// Some things, like a tournament that is still being played, are saved to
+// files so they are still there the next time the game starts. The files go
+// in a folder called "pong" in the place the computer keeps settings for
+// programs. On Linux that is usually ~/.config/pong.
+
+// UserFilePath works out where to keep one of the player's files. If the
+// settings folder can't be found or made, the file goes in the current
+// folder instead.
+func userFilePath(name string) string {
+	var dir string
+	var err error
+	dir, err = os.UserConfigDir()
+	if err != nil {
+		return name
+	}
+	dir = filepath.Join(dir, "pong")
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
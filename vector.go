@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+)
+
+// ---- Vector graphics ----
+//
+// The game can be drawn two ways. Textured graphics draw the pictures in the
+// assets, the way the game has always looked. Vector graphics don't load any
+// pictures at all. The bats, the ball, the net and the digits are drawn with
+// filled rectangles and lines, like the first Pong machine in 1972.
+//
+// Vector graphics use the shapes the theme gives as well as its pictures, so
+// a theme can be drawn either way (see theme.go). The classic theme draws
+// rectangles for the bats and a square ball. The first Pong always had a
+// net, so a theme without a centre line gets a dashed one.
+//
+// Rectangles have sharp edges however much they are stretched, so vector
+// graphics look crisp at any window size.
+
+// The two ways of drawing the game.
+const TexturedGraphics = "textured"
+const VectorGraphics = "vector"
+
+// The sizes of the scores and the game over banner when there are no pictures
+// to measure. The bats and the ball are the same size as on the server (see
+// server.go), so a game with vector graphics plays exactly like one with
+// textured graphics, and the two can play each other.
+const VectorScoreW = 70
+const VectorScoreH = 50
+const VectorGameOverW = 425
+const VectorGameOverH = 49
+
+// The graphics setting: textured or vector.
+var graphicsName string
+
+// The vectorGraphics flag is true if the game is drawn without pictures.
+var vectorGraphics bool
+
+// AddGraphicsFlags adds the command line flag that chooses how the game is
+// drawn.
+func addGraphicsFlags() {
+	flag.StringVar(&graphicsName, "graphics", TexturedGraphics, "how the game is drawn: textured, with the pictures in the assets, or vector, with plain shapes like the 1972 game")
+}
+
+// CheckGraphicsFlags makes sure the graphics setting is one we know.
+func checkGraphicsFlags() error {
+	switch graphicsName {
+	case TexturedGraphics:
+		vectorGraphics = false
+	case VectorGraphics:
+		vectorGraphics = true
+	default:
+		return badSetting("graphics", "the graphics must be textured or vector")
+	}
+	return nil
+}
+
+// SetVectorSizes sets the size of the bats, the ball, the scores and the game
+// over banner, instead of measuring the pictures.
+func setVectorSizes() {
+	setPlainSizes()
+	scoreW = VectorScoreW
+	scoreH = VectorScoreH
+	gameOverW = VectorGameOverW
+	gameOverH = VectorGameOverH
+}
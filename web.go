@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+)
+
+// ---- Playing from a web browser ----
+//
+// The host can also run a small web server. A web browser that visits it is
+// given a web page with a simple version of the game, written in JavaScript.
+// The web page opens a WebSocket back to the host, and then it can play on
+// the right, just like a joining computer, or watch like a spectator.
+//
+// The messages are written in JSON, because JavaScript can read and write
+// JSON easily. The messages are described in PROTOCOL.md. If the messages
+// ever change, WebProtocolVersion must change too, so an old web page is
+// turned away instead of getting confused.
+
+// The version of the web messages.
+const WebProtocolVersion = 1
+
+// The address the web server listens on, for example ":8080". If it is empty
+// there is no web server.
+var webAddress string
+
+// The web page for the browser, in the assets
+const WebPage = "web/pong.html"
+
+// The web server's listener. Closing it stops the web server.
+var webListener net.Listener
+
+// New browser connections arrive on this channel.
+var newWebConnections chan *wsConnection
+
+// The browsers that have connected but have not said hello yet.
+var pendingWebConnections []*wsConnection
+
+// The browser playing on the right, if there is one.
+var webPlayer *wsConnection
+
+// The keys the web player is holding down, using the same bits as rollback.
+var webPlayerInput int
+
+// The browsers that are watching.
+var webSpectators []*wsConnection
+
+// A webMessage is a message from the browser.
+type webMessage struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Role    string `json:"role"`
+	Up      bool   `json:"up"`
+	Down    bool   `json:"down"`
+}
+
+// A webRejected message tells the browser why it cannot play.
+type webRejected struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// A webWelcome message tells the browser the size and shape of everything,
+// so it can draw the game.
+type webWelcome struct {
+	Type      string     `json:"type"`
+	Version   int        `json:"version"`
+	Role      string     `json:"role"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	LeftBatX  int        `json:"leftBatX"`
+	LeftBatW  int        `json:"leftBatW"`
+	LeftBatH  int        `json:"leftBatH"`
+	RightBatX int        `json:"rightBatX"`
+	RightBatW int        `json:"rightBatW"`
+	RightBatH int        `json:"rightBatH"`
+	BallW     int        `json:"ballW"`
+	BallH     int        `json:"ballH"`
+	Bricks    []webBrick `json:"bricks"`
+}
+
+// A webBrick is where one brick is.
+type webBrick struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// A webState message tells the browser where everything is. It is sent
+// every frame.
+type webState struct {
+	Type         string  `json:"type"`
+	LeftBatY     int     `json:"leftBatY"`
+	RightBatY    int     `json:"rightBatY"`
+	BallX        float64 `json:"ballX"`
+	BallY        float64 `json:"ballY"`
+	LeftScore    int     `json:"leftScore"`
+	RightScore   int     `json:"rightScore"`
+	Paused       bool    `json:"paused"`
+	GameOver     bool    `json:"gameOver"`
+	Waiting      bool    `json:"waiting"`
+	SuddenDeath  bool    `json:"suddenDeath"`
+	Clock        string  `json:"clock"`
+	BrokenBricks []bool  `json:"brokenBricks"`
+	Spectators   int     `json:"spectators"`
+}
+
+// StartWebServer starts serving the web page and WebSockets.
+func startWebServer() error {
+	var err error
+	webListener, err = net.Listen("tcp", webAddress)
+	if err != nil {
+		return err
+	}
+	var connections chan *wsConnection
+	connections = make(chan *wsConnection, 16)
+	newWebConnections = connections
+	var mux *http.ServeMux
+	mux = http.NewServeMux()
+	mux.HandleFunc("/", serveWebPage)
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocket(w, r, connections)
+	})
+	go http.Serve(webListener, mux)
+	return nil
+}
+
+// StopWebServer stops the web server and disconnects every browser.
+func stopWebServer() {
+	if webListener != nil {
+		webListener.Close()
+		webListener = nil
+	}
+	var c *wsConnection
+	for _, c = range pendingWebConnections {
+		closeWebSocket(c)
+	}
+	pendingWebConnections = nil
+	for _, c = range webSpectators {
+		closeWebSocket(c)
+	}
+	webSpectators = nil
+	if webPlayer != nil {
+		closeWebSocket(webPlayer)
+		webPlayer = nil
+	}
+}
+
+// ServeWebPage sends the browser the web page.
+func serveWebPage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	var page []byte
+	var err error
+	page, err = readAsset(WebPage)
+	if err != nil {
+		http.Error(w, "the web page is missing", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// ServeWebSocket upgrades a request to a WebSocket and sends it to the game.
+// It runs on one of the web server's goroutines, so it must not touch the
+// game itself.
+func serveWebSocket(w http.ResponseWriter, r *http.Request, connections chan *wsConnection) {
+	var c *wsConnection
+	var err error
+	c, err = upgradeToWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	// if lots of browsers arrive at once, turn some away rather than waiting
+	select {
+	case connections <- c:
+	default:
+		closeWebSocket(c)
+	}
+}
+
+// PollWebClients deals with everything the browsers have sent since the last
+// frame. It must be called once every frame while we are hosting.
+func pollWebClients() {
+	if webListener == nil {
+		return
+	}
+	select {
+	case c := <-newWebConnections:
+		pendingWebConnections = append(pendingWebConnections, c)
+	default:
+	}
+	pollPendingWebConnections()
+	webSpectators = dropLostWebConnections(webSpectators)
+	if webPlayer == nil {
+		return
+	}
+	for {
+		select {
+		case text, ok := <-webPlayer.incoming:
+			if !ok {
+				// the connection has been lost. Pause the game until someone
+				// joins again.
+				closeWebSocket(webPlayer)
+				webPlayer = nil
+				waitForPlayer("CONNECTION LOST - WAITING FOR THE PLAYER TO JOIN AGAIN")
+				return
+			}
+			handleMessageFromWebPlayer(text)
+		default:
+			moveWebPlayersBat()
+			return
+		}
+	}
+}
+
+// PollPendingWebConnections waits for the browsers that have just connected
+// to say hello.
+func pollPendingWebConnections() {
+	var stillPending []*wsConnection
+	var c *wsConnection
+	for _, c = range pendingWebConnections {
+		select {
+		case text, ok := <-c.incoming:
+			if !ok {
+				closeWebSocket(c)
+				continue
+			}
+			var msg webMessage
+			if json.Unmarshal(text, &msg) != nil || msg.Type != "hello" {
+				rejectWebConnection(c, "SAY HELLO FIRST")
+				continue
+			}
+			handleWebHello(c, msg)
+		default:
+			stillPending = append(stillPending, c)
+		}
+	}
+	pendingWebConnections = stillPending
+}
+
+// HandleWebHello decides what to do with a browser that has said hello.
+func handleWebHello(c *wsConnection, msg webMessage) {
+	if msg.Version != WebProtocolVersion {
+		rejectWebConnection(c, "THE HOST IS RUNNING A DIFFERENT VERSION OF PONG")
+		return
+	}
+	if msg.Role == "watch" {
+		webSpectators = append(webSpectators, c)
+		spectatorCount = countSpectators()
+		sendWebMessage(c, makeWebWelcome("watch"))
+		return
+	}
+	if remotePlayer != nil || webPlayer != nil {
+		rejectWebConnection(c, "THE GAME IS FULL - YOU CAN WATCH IT INSTEAD")
+		return
+	}
+	// the player has joined, so we can start playing
+	webPlayer = c
+	webPlayerInput = 0
+	waitingForPlayer = false
+	paused = false
+	networkStatus = ""
+	sendWebMessage(c, makeWebWelcome("play"))
+}
+
+// RejectWebConnection tells a browser why it cannot join, and closes the
+// connection.
+func rejectWebConnection(c *wsConnection, reason string) {
+	sendWebMessage(c, webRejected{Type: "rejected", Reason: reason})
+	closeWebSocket(c)
+}
+
+// DropLostWebConnections reads the messages from some browsers, and returns
+// the ones that are still connected. Spectators only send pings, so we don't
+// need to look at what they say.
+func dropLostWebConnections(connections []*wsConnection) []*wsConnection {
+	var stillConnected []*wsConnection
+	var c *wsConnection
+	for _, c = range connections {
+		var lost bool
+		lost = false
+	readMessages:
+		for {
+			select {
+			case _, ok := <-c.incoming:
+				if !ok {
+					lost = true
+					break readMessages
+				}
+			default:
+				break readMessages
+			}
+		}
+		if lost == true {
+			closeWebSocket(c)
+		} else {
+			stillConnected = append(stillConnected, c)
+		}
+	}
+	return stillConnected
+}
+
+// HandleMessageFromWebPlayer deals with one message from the web player.
+func handleMessageFromWebPlayer(text []byte) {
+	var msg webMessage
+	if json.Unmarshal(text, &msg) != nil {
+		return
+	}
+	switch msg.Type {
+	case "input":
+		webPlayerInput = 0
+		if msg.Up == true {
+			webPlayerInput = webPlayerInput | TickInputUp
+		}
+		if msg.Down == true {
+			webPlayerInput = webPlayerInput | TickInputDown
+		}
+	case "pause":
+		if waitingForPlayer == false && gameOver == false {
+			paused = !paused
+		}
+	}
+}
+
+// MoveWebPlayersBat moves the right bat while the web player holds a key.
+// A browser tells us when a key goes down and when it comes up, so unlike a
+// joining computer we move the bat a little every frame.
+func moveWebPlayersBat() {
+	if paused == true || gameOver == true || waitingForPlayer == true {
+		return
+	}
+	computersBatY = moveBatForInput(computersBatY, computersBatH, computersBatStep(), webPlayerInput)
+}
+
+// SendStateToWebClients tells every browser where everything is. It must be
+// called once every frame.
+func sendStateToWebClients() {
+	if webPlayer == nil && len(webSpectators) == 0 {
+		return
+	}
+	var text []byte
+	var err error
+	text, err = json.Marshal(makeWebState())
+	if err != nil {
+		return
+	}
+	if webPlayer != nil {
+		sendWebSocketText(webPlayer, text)
+	}
+	var c *wsConnection
+	for _, c = range webSpectators {
+		sendWebSocketText(c, text)
+	}
+}
+
+// SendWebMessage turns a message into JSON and sends it to a browser.
+func sendWebMessage(c *wsConnection, msg interface{}) {
+	var text []byte
+	var err error
+	text, err = json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	sendWebSocketText(c, text)
+}
+
+// MakeWebWelcome collects the sizes of everything for the welcome message.
+func makeWebWelcome(role string) webWelcome {
+	var w webWelcome
+	w.Type = "welcome"
+	w.Version = WebProtocolVersion
+	w.Role = role
+	w.Width = windowWidth
+	w.Height = windowHeight
+	w.LeftBatX = myBatX
+	w.LeftBatW = myBatW
+	w.LeftBatH = myBatH
+	w.RightBatX = computersBatX
+	w.RightBatW = computersBatW
+	w.RightBatH = computersBatH
+	w.BallW = ballW
+	w.BallH = ballH
+	w.Bricks = []webBrick{}
+	var i int
+	for i = 0; i < len(bricks); i++ {
+		w.Bricks = append(w.Bricks, webBrick{X: bricks[i].x, Y: bricks[i].y, W: bricks[i].w, H: bricks[i].h})
+	}
+	return w
+}
+
+// MakeWebState collects where everything is for the state message.
+func makeWebState() webState {
+	var s webState
+	s.Type = "state"
+	s.LeftBatY = myBatY
+	s.RightBatY = computersBatY
+	s.BallX = ballX
+	s.BallY = ballY
+	s.LeftScore = myScore
+	s.RightScore = computersScore
+	s.Paused = paused
+	s.GameOver = gameOver
+	s.Waiting = waitingForPlayer
+	s.SuddenDeath = suddenDeath
+	if isTimedMatch() == true {
+		s.Clock = formatClock(timeLeft())
+	}
+	s.BrokenBricks = []bool{}
+	var i int
+	for i = 0; i < len(bricks); i++ {
+		s.BrokenBricks = append(s.BrokenBricks, bricks[i].broken)
+	}
+	s.Spectators = spectatorCount
+	return s
+}
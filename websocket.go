@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ---- WebSockets ----
+//
+// A web browser can't open a plain TCP connection like the one the game uses
+// to talk to another copy of the game. Instead it can open a WebSocket. A
+// WebSocket starts life as an ordinary web request. The browser asks the web
+// server to "upgrade" the connection, and if the server agrees, the same
+// connection is then used to send messages both ways.
+//
+// Each message is sent in a frame. A frame starts with a couple of bytes that
+// say what kind of frame it is and how long it is. Frames sent by the browser
+// are also "masked", which means every byte has been mixed up with one of four
+// mask bytes, and we have to unmix them. The rules are written down in a
+// document called RFC 6455. We only need the simple parts of it.
+
+// The browser's key is joined to this string to make the reply to the
+// upgrade request. It is the same for every WebSocket server in the world.
+const WebSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// The kinds of frame
+const (
+	WsContinuation = 0x0
+	WsText         = 0x1
+	WsBinary       = 0x2
+	WsClose        = 0x8
+	WsPing         = 0x9
+)
+
+// The longest message we will read from a browser. The browser only ever
+// sends short messages, so anything longer is a mistake.
+const MaxWebSocketMessage = 4096
+
+// A wsFrame is one frame to send to the browser.
+type wsFrame struct {
+	opcode  byte
+	payload []byte
+}
+
+// A wsConnection is a WebSocket connection to a browser. Like a
+// netConnection, messages are read and written by their own goroutines. The
+// messages the browser sends arrive on the incoming channel, and the frames
+// to send go on the outgoing channel. The incoming channel is closed when the
+// connection is lost.
+type wsConnection struct {
+	conn     net.Conn
+	incoming chan []byte
+	outgoing chan wsFrame
+}
+
+// UpgradeToWebSocket agrees to the browser's request to upgrade the
+// connection, and starts reading and writing messages.
+func upgradeToWebSocket(w http.ResponseWriter, r *http.Request) (*wsConnection, error) {
+	if strings.Contains(strings.ToLower(r.Header.Get("Upgrade")), "websocket") == false {
+		http.Error(w, "this address is for WebSockets", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket request")
+	}
+	var key string
+	key = r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusBadRequest)
+		return nil, errors.New("unsupported WebSocket version")
+	}
+	// a browser says which web site the page opening the WebSocket came
+	// from. Only our own page may play, so another web site can't join a
+	// game from the browser of someone who happens to visit it.
+	if sameOrigin(r) == false {
+		http.Error(w, "this WebSocket is only for the game's own page", http.StatusForbidden)
+		return nil, errors.New("the WebSocket request came from another web site")
+	}
+	var hijacker http.Hijacker
+	var ok bool
+	hijacker, ok = w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "cannot upgrade this connection", http.StatusInternalServerError)
+		return nil, errors.New("cannot hijack the connection")
+	}
+	var conn net.Conn
+	var buffers *bufio.ReadWriter
+	var err error
+	conn, buffers, err = hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// the reply proves to the browser that we understood its request
+	var hash [20]byte
+	hash = sha1.Sum([]byte(key + WebSocketGUID))
+	buffers.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	buffers.WriteString("Upgrade: websocket\r\n")
+	buffers.WriteString("Connection: Upgrade\r\n")
+	buffers.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	err = buffers.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var c wsConnection
+	c.conn = conn
+	c.incoming = make(chan []byte, 16)
+	c.outgoing = make(chan wsFrame, 16)
+	go readWebSocket(&c, buffers.Reader)
+	go writeWebSocket(&c)
+	return &c, nil
+}
+
+// SameOrigin is true if the request came from a page on the web server it
+// was sent to. A request without an Origin didn't come from a browser page,
+// so it isn't allowed either.
+func sameOrigin(r *http.Request) bool {
+	var origin string
+	origin = r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	var u *url.URL
+	var err error
+	u, err = url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// ReadWebSocket reads messages from the browser until the connection is
+// closed. If nothing arrives for a while the connection has been lost.
+func readWebSocket(c *wsConnection, r *bufio.Reader) {
+	defer close(c.incoming)
+	var message []byte
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(NetworkTimeout))
+		var fin bool
+		var opcode byte
+		var payload []byte
+		var err error
+		fin, opcode, payload, err = readWebSocketFrame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case WsText, WsBinary, WsContinuation:
+			message = append(message, payload...)
+			if len(message) > MaxWebSocketMessage {
+				return
+			}
+			if fin == true {
+				c.incoming <- message
+				message = nil
+			}
+		case WsClose:
+			return
+		}
+		// browsers never send pings, so we don't need to answer them
+	}
+}
+
+// ReadWebSocketFrame reads one frame. It returns whether this is the final
+// frame of a message, the kind of frame and what it contains.
+func readWebSocketFrame(r *bufio.Reader) (bool, byte, []byte, error) {
+	var header [2]byte
+	var err error
+	_, err = io.ReadFull(r, header[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	var fin bool
+	fin = header[0]&0x80 != 0
+	var opcode byte
+	opcode = header[0] & 0x0F
+	var masked bool
+	masked = header[1]&0x80 != 0
+	// the length is either in the header, or in the next 2 or 8 bytes
+	var length uint64
+	length = uint64(header[1] & 0x7F)
+	if length == 126 {
+		var extended [2]byte
+		_, err = io.ReadFull(r, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	} else if length == 127 {
+		var extended [8]byte
+		_, err = io.ReadFull(r, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if length > MaxWebSocketMessage {
+		return false, 0, nil, errors.New("WebSocket frame too long")
+	}
+	// browsers must always mask what they send
+	if masked == false {
+		return false, 0, nil, errors.New("WebSocket frame not masked")
+	}
+	var mask [4]byte
+	_, err = io.ReadFull(r, mask[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	var payload []byte
+	payload = make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	var i int
+	for i = 0; i < len(payload); i++ {
+		payload[i] = payload[i] ^ mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteWebSocket writes frames to the browser until the outgoing channel is
+// closed, and then closes the connection.
+func writeWebSocket(c *wsConnection) {
+	var frame wsFrame
+	for frame = range c.outgoing {
+		c.conn.SetWriteDeadline(time.Now().Add(NetworkTimeout))
+		var err error
+		_, err = c.conn.Write(makeWebSocketFrame(frame))
+		if err != nil {
+			// the reader will notice the connection has gone
+			c.conn.Close()
+			return
+		}
+	}
+	// say goodbye properly, so the browser knows we meant to close it
+	c.conn.Write(makeWebSocketFrame(wsFrame{opcode: WsClose}))
+	c.conn.Close()
+}
+
+// MakeWebSocketFrame turns a frame into bytes. Frames sent to the browser
+// are never masked.
+func makeWebSocketFrame(frame wsFrame) []byte {
+	var b []byte
+	b = append(b, 0x80|frame.opcode)
+	var length int
+	length = len(frame.payload)
+	if length < 126 {
+		b = append(b, byte(length))
+	} else if length < 65536 {
+		b = append(b, 126, byte(length>>8), byte(length))
+	} else {
+		var extended [8]byte
+		binary.BigEndian.PutUint64(extended[:], uint64(length))
+		b = append(b, 127)
+		b = append(b, extended[:]...)
+	}
+	return append(b, frame.payload...)
+}
+
+// SendWebSocketFrame queues a frame to send. Like sendMessage, if the
+// browser is not keeping up the frame is dropped rather than stopping the game.
+func sendWebSocketFrame(c *wsConnection, frame wsFrame) {
+	select {
+	case c.outgoing <- frame:
+	default:
+	}
+}
+
+// SendWebSocketText queues a text message to send.
+func sendWebSocketText(c *wsConnection, text []byte) {
+	sendWebSocketFrame(c, wsFrame{opcode: WsText, payload: text})
+}
+
+// CloseWebSocket closes the connection once everything queued has been sent.
+func closeWebSocket(c *wsConnection) {
+	close(c.outgoing)
+}
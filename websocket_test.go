@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebSocketFrames(t *testing.T) {
+	// a browser masks everything it sends
+	var mask = []byte{1, 2, 3, 4}
+	var message = []byte("hello")
+	var frame []byte
+	frame = []byte{0x80 | WsText, 0x80 | byte(len(message))}
+	frame = append(frame, mask...)
+	var i int
+	for i = 0; i < len(message); i++ {
+		frame = append(frame, message[i]^mask[i%4])
+	}
+	var fin bool
+	var opcode byte
+	var payload []byte
+	var err error
+	fin, opcode, payload, err = readWebSocketFrame(bufio.NewReader(bytes.NewReader(frame)))
+	if err != nil || fin == false || opcode != WsText || string(payload) != "hello" {
+		t.Errorf("read the frame as %v %v %q %v", fin, opcode, payload, err)
+	}
+	// frames that aren't masked are turned away
+	_, _, _, err = readWebSocketFrame(bufio.NewReader(bytes.NewReader(makeWebSocketFrame(wsFrame{opcode: WsText, payload: message}))))
+	if err == nil {
+		t.Error("read a frame that wasn't masked")
+	}
+	// a long frame has its length in the next two bytes
+	var long []byte
+	long = makeWebSocketFrame(wsFrame{opcode: WsBinary, payload: make([]byte, 300)})
+	if len(long) != 4+300 || long[1] != 126 || long[2] != 1 || long[3] != 44 {
+		t.Errorf("a long frame starts %v", long[:4])
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	var tests = []struct {
+		origin string
+		ok     bool
+	}{
+		{"http://localhost:8080", true},
+		{"http://LOCALHOST:8080", true},
+		{"", false},
+		{"http://localhost:9090", false},
+		{"http://example.com", false},
+		{"null", false},
+	}
+	var test struct {
+		origin string
+		ok     bool
+	}
+	for _, test = range tests {
+		var r *http.Request
+		var err error
+		r, err = http.NewRequest("GET", "http://localhost:8080/ws", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if sameOrigin(r) != test.ok {
+			t.Errorf("the origin %q should be allowed: %v", test.origin, test.ok)
+		}
+	}
+}
+
+// TestUpgradeToWebSocket asks a web server for a WebSocket the way a browser
+// does, from the game's own page and from another web site.
+func TestUpgradeToWebSocket(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c *wsConnection
+		var err error
+		c, err = upgradeToWebSocket(w, r)
+		if err == nil {
+			close(c.outgoing)
+		}
+	}))
+	defer server.Close()
+	var tests = []struct {
+		origin string
+		status int
+	}{
+		{"http://" + server.Listener.Addr().String(), http.StatusSwitchingProtocols},
+		{"http://example.com", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	var test struct {
+		origin string
+		status int
+	}
+	for _, test = range tests {
+		var conn net.Conn
+		var err error
+		conn, err = net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var request string
+		request = "GET /ws HTTP/1.1\r\nHost: " + server.Listener.Addr().String() + "\r\n"
+		if test.origin != "" {
+			request = request + "Origin: " + test.origin + "\r\n"
+		}
+		request = request + "Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+		conn.Write([]byte(request))
+		var response *http.Response
+		response, err = http.ReadResponse(bufio.NewReader(conn), nil)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.status {
+			t.Errorf("the origin %q got %d, not %d", test.origin, response.StatusCode, test.status)
+		}
+		// the example key and answer from the WebSocket standard
+		if test.status == http.StatusSwitchingProtocols && response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+			t.Errorf("the answer to the key is %q", response.Header.Get("Sec-WebSocket-Accept"))
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ---- The window ----
+//
+// The game is always drawn on a playing field that is FieldWidth by
+// FieldHeight pixels, whatever size the window is. Everything in the game,
+// the bats, the ball, the text, is positioned on the playing field.
+//
+// The playing field is a texture - a picture the graphics card can draw
+// into. At the end of every frame the playing field is stretched to fit the
+// window and drawn into it. If the window is a different shape to the
+// playing field there are black bars down the sides or along the top and
+// bottom, so the game is never squashed. This is called letterboxing.
+//
+// Because the playing field is always the same size, two computers with
+// different sized windows can play each other, and a replay looks the same
+// on any computer.
+//
+// The window can be resized with the mouse, and F11 switches between a
+// window and the full screen.
+
+// The size of the playing field in pixels.
+const FieldWidth = 1024
+const FieldHeight = 768
+
+// The smallest and biggest windows we allow.
+const MinimumWindowWidth = 320
+const MinimumWindowHeight = 240
+const MaximumWindowWidth = 7680
+const MaximumWindowHeight = 4320
+
+// The kinds of full screen. Desktop full screen is a window without a border
+// that covers the whole screen, which is quick to switch to. Exclusive full
+// screen changes the screen's resolution to the size of the window.
+const DesktopFullscreen = "desktop"
+const ExclusiveFullscreen = "exclusive"
+
+// The size the window starts at, before it is resized.
+var startWindowWidth int
+var startWindowHeight int
+
+// The full screen setting: off, desktop or exclusive.
+var fullscreenName string
+
+// The fullscreen flag is true while the game covers the whole screen.
+var fullscreen bool
+
+// The texture the game is drawn into.
+var playfield *sdl.Texture
+
+// Where the playing field is drawn in the window.
+var letterbox sdl.Rect
+
+// AddWindowFlags adds the command line flags for the window.
+func addWindowFlags() {
+	flag.IntVar(&startWindowWidth, "width", FieldWidth, "the width of the window in pixels")
+	flag.IntVar(&startWindowHeight, "height", FieldHeight, "the height of the window in pixels")
+	flag.StringVar(&fullscreenName, "fullscreen", "off", "start the game full screen: off, desktop or exclusive")
+}
+
+// CheckWindowFlags makes sure the window settings make sense.
+func checkWindowFlags() error {
+	if startWindowWidth < MinimumWindowWidth || startWindowWidth > MaximumWindowWidth {
+		return badSetting("width", fmt.Sprintf("the window must be between %d and %d pixels wide", MinimumWindowWidth, MaximumWindowWidth))
+	}
+	if startWindowHeight < MinimumWindowHeight || startWindowHeight > MaximumWindowHeight {
+		return badSetting("height", fmt.Sprintf("the window must be between %d and %d pixels high", MinimumWindowHeight, MaximumWindowHeight))
+	}
+	switch fullscreenName {
+	case "off":
+		fullscreen = false
+	case DesktopFullscreen, ExclusiveFullscreen:
+		fullscreen = true
+	default:
+		return badSetting("fullscreen", "the full screen setting must be off, desktop or exclusive")
+	}
+	return nil
+}
+
+// WindowFlags works out the flags the window is created with.
+func windowFlags() uint32 {
+	// exporting a replay draws into a window nobody can see
+	if exportFilename != "" {
+		return sdl.WINDOW_HIDDEN
+	}
+	var flags uint32
+	flags = sdl.WINDOW_SHOWN | sdl.WINDOW_RESIZABLE
+	if fullscreen == true {
+		flags = flags | fullscreenFlag()
+	}
+	return flags
+}
+
+// FullscreenFlag is the SDL flag for the kind of full screen that was
+// chosen. If full screen is off, F11 uses desktop full screen.
+func fullscreenFlag() uint32 {
+	if fullscreenName == ExclusiveFullscreen {
+		return sdl.WINDOW_FULLSCREEN
+	}
+	return sdl.WINDOW_FULLSCREEN_DESKTOP
+}
+
+// CreatePlayfield creates the texture the game is drawn into, and tells the
+// renderer to draw into it. The renderer must be able to draw into textures.
+func createPlayfield() {
+	var err error
+	playfield, err = renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_TARGET, windowWidth, windowHeight)
+	if err != nil {
+		fmt.Print("Failed to create the playing field: ")
+		fmt.Println(err)
+		panic(err)
+	}
+	renderer.SetRenderTarget(playfield)
+	fitPlayfieldToWindow()
+}
+
+// FitPlayfieldToWindow works out where the playing field goes in the window.
+// It is made as big as it can be without changing its shape, and put in the
+// middle. It is called whenever the window changes size.
+func fitPlayfieldToWindow() {
+	var w, h int
+	var err error
+	// the size of the window in real pixels, which on some screens is
+	// bigger than the size of the window
+	w, h, err = renderer.GetRendererOutputSize()
+	if err != nil || w == 0 || h == 0 {
+		w, h = window.GetSize()
+	}
+	if w == 0 || h == 0 {
+		// the window is minimised, so there is nowhere to draw
+		letterbox = sdl.Rect{X: 0, Y: 0, W: 0, H: 0}
+		return
+	}
+	var scale float64
+	scale = float64(w) / float64(windowWidth)
+	if float64(h)/float64(windowHeight) < scale {
+		scale = float64(h) / float64(windowHeight)
+	}
+	var fieldW, fieldH int
+	fieldW = int(float64(windowWidth) * scale)
+	fieldH = int(float64(windowHeight) * scale)
+	letterbox = sdl.Rect{X: int32((w - fieldW) / 2), Y: int32((h - fieldH) / 2), W: int32(fieldW), H: int32(fieldH)}
+}
+
+// HandleWindowEvent fits the playing field to the window again when the
+// window changes size, and switches to and from full screen when F11 is
+// pressed. It returns true if it used the event.
+func handleWindowEvent(event sdl.Event) bool {
+	var windowEvt *sdl.WindowEvent
+	var ok bool
+	windowEvt, ok = event.(*sdl.WindowEvent)
+	if ok == true {
+		if windowEvt.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+			fitPlayfieldToWindow()
+		}
+		return true
+	}
+	if isKeyDownEvent(event) == false {
+		return false
+	}
+	var keyDownEvt *sdl.KeyDownEvent
+	keyDownEvt = event.(*sdl.KeyDownEvent)
+	if keyDownEvt.Keysym.Sym != sdl.K_F11 {
+		return false
+	}
+	toggleFullscreen()
+	return true
+}
+
+// ToggleFullscreen switches between a window and the full screen.
+func toggleFullscreen() {
+	var flags uint32
+	if fullscreen == false {
+		flags = fullscreenFlag()
+	}
+	var err error
+	err = window.SetFullscreen(flags)
+	if err != nil {
+		fmt.Print("Failed to change to or from full screen: ")
+		fmt.Println(err)
+		return
+	}
+	if fullscreen == true {
+		fullscreen = false
+	} else {
+		fullscreen = true
+	}
+	fitPlayfieldToWindow()
+}
+
+// PresentPlayfield draws the playing field into the window and shows it, then
+// gets the renderer ready to draw the next frame into the playing field.
+func presentPlayfield() {
+	// the letterbox bars are black, but the game might be drawing in
+	// another colour, so remember it and put it back afterwards
+	var r, g, b, a uint8
+	r, g, b, a, _ = renderer.GetDrawColor()
+	renderer.SetRenderTarget(nil)
+	renderer.SetDrawColor(0, 0, 0, 255)
+	renderer.Clear()
+	renderer.Copy(playfield, nil, &letterbox)
+	renderer.Present()
+	renderer.SetRenderTarget(playfield)
+	renderer.SetDrawColor(r, g, b, a)
+}